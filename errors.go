@@ -0,0 +1,37 @@
+package routeprotection
+
+import (
+	"errors"
+
+	"github.com/terra-discover/bbcrs-route-protection-lib/middleware"
+	"github.com/terra-discover/bbcrs-route-protection-lib/migration"
+)
+
+// ErrNotMigrated - relation schema is not found, MigrateRelation must be called first
+var ErrNotMigrated = errors.New("RouteProtection: No relation schema found, please MigrateRelation first")
+
+// Errors of middleware and migration, so callers only need to import this package.
+// RouteProtection.Error joins every error by errors.Join, use errors.Is or errors.As to inspect it.
+var (
+	ErrRouterSourceEmpty     = middleware.ErrRouterSourceEmpty
+	ErrModelSchemasEmpty     = middleware.ErrModelSchemasEmpty
+	ErrFiberAppEmpty         = middleware.ErrFiberAppEmpty
+	ErrFiberAppNotStarted    = middleware.ErrFiberAppNotStarted
+	ErrRouteFSEmpty          = middleware.ErrRouteFSEmpty
+	ErrDeleteLockUnsupported = middleware.ErrDeleteLockUnsupported
+	ErrInvalidMigrationModel = migration.ErrInvalidMigrationModel
+	ErrModelSchema           = migration.ErrModelSchema
+	ErrColumnTableNotFound   = migration.ErrColumnTableNotFound
+	ErrColumnConflict        = migration.ErrColumnConflict
+)
+
+type (
+	Issue                    = middleware.Issue
+	ValidationReport         = middleware.ValidationReport
+	RouteValidationError     = middleware.RouteValidationError
+	ProtectionQueryError     = middleware.ProtectionQueryError
+	ProtectionLoadError      = middleware.ProtectionLoadError
+	RequestError             = middleware.RequestError
+	ProtectionViolationError = middleware.ProtectionViolationError
+	MigrationError           = migration.MigrationError
+)
@@ -9,6 +9,7 @@ require (
 	github.com/iancoleman/strcase v0.1.3
 	github.com/terra-discover/bbcrs-helper-lib v0.0.0-20241227033510-ceec69855417
 	github.com/terra-discover/bbcrs-migration-lib v0.0.0-20241227043856-e4d9433cdcfc
+	gopkg.in/yaml.v3 v3.0.1
 	gorm.io/gorm v1.25.4
 )
 
@@ -67,7 +68,6 @@ require (
 	golang.org/x/sys v0.13.0 // indirect
 	golang.org/x/text v0.13.0 // indirect
 	gopkg.in/ini.v1 v1.67.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 	gorm.io/driver/postgres v1.5.2 // indirect
 	modernc.org/libc v1.22.5 // indirect
 	modernc.org/mathutil v1.5.0 // indirect
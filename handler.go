@@ -0,0 +1,141 @@
+package routeprotection
+
+import (
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/terra-discover/bbcrs-helper-lib/pkg/lib"
+	"github.com/terra-discover/bbcrs-route-protection-lib/middleware"
+)
+
+// FailPolicy - decide what to do with the request when route protection cannot run,
+// ex: relation not migrated yet, route not mapped yet or protection query is failed.
+// It is the same type as middleware.FailurePolicy, see RouteProtection.SetFailurePolicy.
+type FailPolicy = middleware.FailurePolicy
+
+const (
+	// FailClosed - reject the request, using Config.ErrorHandler if route protection cannot run,
+	// or 503 Service Unavailable if protection query is failed
+	FailClosed = middleware.FailClosed
+	// FailClosedInternal - reject the request, using Config.ErrorHandler if route protection cannot run,
+	// or 500 Internal Server Error if protection query is failed
+	FailClosedInternal = middleware.FailClosedInternal
+	// FailOpen - log the error and continue to the next handler
+	FailOpen = middleware.FailOpen
+	// FailCallback - defer to Config.FailureCallback
+	FailCallback = middleware.FailCallback
+)
+
+// Config - configuration of Handler
+type Config struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c *fiber.Ctx) bool
+
+	// ErrorHandler is called when route protection cannot run and Policy is FailClosed.
+	//
+	// Optional. Default: response 500 Internal Server Error
+	ErrorHandler fiber.ErrorHandler
+
+	// Policy when route protection cannot run or protection query is failed.
+	// If set, Policy replaces failure policy of RouteProtection.SetFailurePolicy,
+	// otherwise failure policy is kept and route protection which cannot run is FailClosed.
+	//
+	// Optional. Default: nil
+	Policy *FailPolicy
+
+	// FailureCallback is called when Policy is FailCallback.
+	//
+	// Optional. Default: nil
+	FailureCallback middleware.FailureCallback
+}
+
+// ConfigDefault is the default config
+var ConfigDefault = Config{
+	Next: nil,
+	ErrorHandler: func(c *fiber.Ctx, err error) error {
+		return lib.ErrorInternal(c, err.Error())
+	},
+	Policy: nil,
+}
+
+// configDefault - set default value of empty config
+func configDefault(config ...Config) (cfg Config) {
+	if len(config) < 1 {
+		cfg = ConfigDefault
+		return
+	}
+
+	cfg = config[0]
+	if cfg.ErrorHandler == nil {
+		cfg.ErrorHandler = ConfigDefault.ErrorHandler
+	}
+
+	return
+}
+
+// Handler - create fiber.Handler to protect all mapped routes.
+// MigrateRelation and MappingRoute must be called before serving requests.
+//
+// Example:
+//
+//	rp := routeprotection.NewRouteProtection(env, db)
+//	policy := routeprotection.FailOpen
+//	app.Use(rp.Handler(routeprotection.Config{
+//		Policy: &policy,
+//	}))
+//
+// Panic if FailCallback policy is set without FailureCallback.
+func (rp *RouteProtection) Handler(config ...Config) fiber.Handler {
+	cfg := configDefault(config...)
+
+	// Only policy which is set replaces failure policy of data protection
+	if cfg.Policy != nil {
+		if *cfg.Policy == FailCallback && cfg.FailureCallback == nil {
+			panic("RouteProtection Handler: FailureCallback is required by FailCallback policy")
+		}
+
+		rp.middleware.SetFailurePolicy(*cfg.Policy, cfg.FailureCallback)
+	}
+
+	return func(c *fiber.Ctx) error {
+		// Skip middleware
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		if err := rp.isMigrated(); err != nil {
+			return cfg.fail(c, err)
+		}
+
+		// Protect will continue to next handler, so err may come from next handler
+		err := rp.middleware.Protect(c)
+		if errors.Is(err, middleware.ErrRouterSourceEmpty) {
+			return cfg.fail(c, err)
+		}
+
+		return err
+	}
+}
+
+// fail - apply fail policy, FailClosed if policy is not set
+func (cfg Config) fail(c *fiber.Ctx, err error) error {
+	policy := FailClosed
+	if cfg.Policy != nil {
+		policy = *cfg.Policy
+	}
+
+	switch policy {
+	case FailOpen:
+		log.Println("WARNING RouteProtection Handler: route protection is skipped,", err.Error())
+		return c.Next()
+	case FailCallback:
+		if cfg.FailureCallback != nil {
+			return cfg.FailureCallback(c, err)
+		}
+	}
+
+	return cfg.ErrorHandler(c, err)
+}
@@ -0,0 +1,193 @@
+package routeprotection
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/google/uuid"
+	"github.com/terra-discover/bbcrs-helper-lib/pkg/lib"
+	standardModel "github.com/terra-discover/bbcrs-migration-lib/model"
+	"github.com/terra-discover/bbcrs-route-protection-lib/middleware"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// testSupport__DBConnectTest - relation schema and city are migrated, airport is not migrated,
+// so protection query of city is failed
+func testSupport__DBConnectTest(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+		NamingStrategy:                           schema.NamingStrategy{SingularTable: true},
+	})
+	utils.AssertEqual(t, nil, err, "open db")
+
+	sqlDB, _ := db.DB()
+	// in memory database only live on single connection
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	err = db.AutoMigrate(&standardModel.RelationSchema{}, &standardModel.City{})
+	utils.AssertEqual(t, nil, err, "migrate")
+
+	relationSchema := standardModel.RelationSchema{
+		ColumnSource: lib.Strptr("id"),
+		TableSource:  lib.Strptr("city"),
+		UsedByColumn: lib.Strptr("city_id"),
+		UsedByTable:  lib.Strptr("airport"),
+	}
+	err = db.Create(&relationSchema).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	return db
+}
+
+func TestRouteProtection_Handler(t *testing.T) {
+	db := testSupport__DBConnectTest(t)
+
+	errHandler := func(c *fiber.Ctx, err error) error {
+		return c.Status(fiber.StatusTeapot).SendString(err.Error())
+	}
+	failureCallback := func(c *fiber.Ctx, err error) error {
+		return c.Status(fiber.StatusAccepted).SendString(err.Error())
+	}
+	policy := func(p FailPolicy) *FailPolicy {
+		return &p
+	}
+
+	tests := []struct {
+		name         string
+		isMigrated   bool
+		isMapped     bool
+		config       []Config
+		wantStatus   int
+		wantResponse string
+	}{
+		{
+			name:       "skipped by next",
+			config:     []Config{{Next: func(c *fiber.Ctx) bool { return true }}},
+			wantStatus: fiber.StatusOK,
+		},
+		{
+			name:         "not migrated, error handler",
+			config:       []Config{{ErrorHandler: errHandler}},
+			wantStatus:   fiber.StatusTeapot,
+			wantResponse: ErrNotMigrated.Error(),
+		},
+		{
+			name:         "router source empty, error handler",
+			isMigrated:   true,
+			config:       []Config{{ErrorHandler: errHandler}},
+			wantStatus:   fiber.StatusTeapot,
+			wantResponse: middleware.ErrRouterSourceEmpty.Error(),
+		},
+		{
+			name:       "not migrated, fail open",
+			config:     []Config{{Policy: policy(FailOpen)}},
+			wantStatus: fiber.StatusOK,
+		},
+		{
+			name:         "not migrated, fail callback",
+			config:       []Config{{Policy: policy(FailCallback), FailureCallback: failureCallback}},
+			wantStatus:   fiber.StatusAccepted,
+			wantResponse: ErrNotMigrated.Error(),
+		},
+		{
+			name:       "protection query is failed, fail open",
+			isMigrated: true,
+			isMapped:   true,
+			config:     []Config{{Policy: policy(FailOpen)}},
+			wantStatus: fiber.StatusOK,
+		},
+		{
+			name:       "protection query is failed, fail closed",
+			isMigrated: true,
+			isMapped:   true,
+			config:     []Config{{Policy: policy(FailClosed)}},
+			wantStatus: fiber.StatusServiceUnavailable,
+		},
+		{
+			name:       "protection query is failed, default config",
+			isMigrated: true,
+			isMapped:   true,
+			wantStatus: fiber.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := NewRouteProtection(Environment{}, db)
+			rp.migration.IsMigrated = tt.isMigrated
+			if tt.isMapped {
+				rp.ReplaceRouterSource(middleware.RouterSource{
+					middleware.UseMasterPattern("cities"): middleware.SourceRelation{
+						Source: "city",
+					},
+				})
+				utils.AssertEqual(t, nil, rp.Error, "validate err")
+			}
+
+			app := fiber.New()
+			app.Use(rp.Handler(tt.config...))
+			app.Delete("/api/v1/master/cities/:id", func(c *fiber.Ctx) error {
+				return c.SendStatus(fiber.StatusOK)
+			})
+
+			req := httptest.NewRequest(fiber.MethodDelete, "/api/v1/master/cities/"+uuid.NewString(), nil)
+			resp, err := app.Test(req, -1)
+			utils.AssertEqual(t, nil, err, "app test")
+			utils.AssertEqual(t, tt.wantStatus, resp.StatusCode, "validate status")
+
+			if tt.wantResponse != "" {
+				body := make([]byte, len(tt.wantResponse))
+				_, _ = resp.Body.Read(body)
+				utils.AssertEqual(t, tt.wantResponse, string(body), "validate response")
+			}
+		})
+	}
+}
+
+func TestRouteProtection_Handler_keepFailurePolicy(t *testing.T) {
+	db := testSupport__DBConnectTest(t)
+
+	rp := NewRouteProtection(Environment{}, db)
+	rp.migration.IsMigrated = true
+	rp.ReplaceRouterSource(middleware.RouterSource{
+		middleware.UseMasterPattern("cities"): middleware.SourceRelation{
+			Source: "city",
+		},
+	}).SetFailurePolicy(FailOpen)
+	utils.AssertEqual(t, nil, rp.Error, "validate err")
+
+	// Config without Policy keeps FailOpen of SetFailurePolicy
+	app := fiber.New()
+	app.Use(rp.Handler(Config{Next: func(c *fiber.Ctx) bool { return false }}))
+	app.Delete("/api/v1/master/cities/:id", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	req := httptest.NewRequest(fiber.MethodDelete, "/api/v1/master/cities/"+uuid.NewString(), nil)
+	resp, err := app.Test(req, -1)
+	utils.AssertEqual(t, nil, err, "app test")
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode, "validate status")
+}
+
+func TestRouteProtection_Handler_failCallbackWithoutCallback(t *testing.T) {
+	db := testSupport__DBConnectTest(t)
+	rp := NewRouteProtection(Environment{}, db)
+
+	defer func() {
+		r := recover()
+		utils.AssertEqual(t, true, r != nil, "validate panic")
+	}()
+
+	policy := FailCallback
+	rp.Handler(Config{Policy: &policy})
+	t.Error("Handler must panic")
+}
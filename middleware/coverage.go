@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"github.com/terra-discover/bbcrs-helper-lib/pkg/lib"
+)
+
+// CoverageConfig - coverage analysis of DELETE and batch delete routes of router file, run by MappingRoute
+type CoverageConfig struct {
+	// Allowlist - routes which are intentionally unprotected, as written on router file with router prefix.
+	// Entry is matched as exact route or as regex of the whole route, ex: "/api/v1/master/logs/:id" or ".*/logs/.*"
+	Allowlist []string
+	// IsGapError - uncovered route is reported with SeverityError instead of SeverityWarning
+	IsGapError bool
+}
+
+// isAllowed - route is listed on allowlist
+func (cfg CoverageConfig) isAllowed(route string) bool {
+	for _, allowed := range cfg.Allowlist {
+		if allowed == route {
+			return true
+		}
+
+		pattern, err := regexp.Compile("^(?:" + allowed + ")$")
+		if err == nil && pattern.MatchString(route) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRouteCoverage - DELETE and batch delete routes which are not covered by any pattern of router source.
+// Batch action route with path parameter on action or module (ex: /batch-actions/:action/:module) is generic,
+// its module is decided per request, so it is not reported.
+func checkRouteCoverage(routerSource RouterSource, listDeleteRoute, listPostRoute []MappingRoute, cfg CoverageConfig) (issues []Issue) {
+	listPattern := []*regexp.Regexp{}
+	mapModule := make(map[string]bool)
+	for routePattern := range routerSource {
+		pattern, err := regexp.Compile(routePattern)
+		if err != nil {
+			// Already reported by validateRoute
+			continue
+		}
+
+		listPattern = append(listPattern, pattern)
+		if routerModule := getRoutePatternModule(routePattern); !lib.IsEmptyStr(routerModule) {
+			mapModule[strcase.ToSnake(routerModule)] = true
+		}
+	}
+
+	severity := SeverityWarning
+	if cfg.IsGapError {
+		severity = SeverityError
+	}
+
+loopDeleteRoute:
+	for _, route := range listDeleteRoute {
+		if cfg.isAllowed(route.Path) {
+			continue loopDeleteRoute
+		}
+
+		for _, pattern := range listPattern {
+			if pattern.MatchString(route.Path) {
+				continue loopDeleteRoute
+			}
+		}
+
+		issues = append(issues, Issue{
+			Code:       IssueRouteUncovered,
+			Severity:   severity,
+			Route:      route.Path,
+			Message:    fmt.Sprintf("DELETE route %s is not covered by any pattern", route.Path),
+			Suggestion: "Add a pattern of the route to router source, or add the route to coverage allowlist if it is intentionally unprotected",
+		})
+	}
+
+	batchPattern := regexp.MustCompile(batchActionRoutePattern)
+
+loopPostRoute:
+	for _, route := range listPostRoute {
+		result := batchPattern.FindStringSubmatch(route.Path)
+		if len(result) < 3 || cfg.isAllowed(route.Path) {
+			continue loopPostRoute
+		}
+
+		action, module := result[1], result[2]
+		if strings.HasPrefix(action, ":") || strings.HasPrefix(module, ":") || action != deleteBatchAction {
+			continue loopPostRoute
+		}
+
+		if mapModule[strcase.ToSnake(module)] {
+			continue loopPostRoute
+		}
+
+		issues = append(issues, Issue{
+			Code:       IssueRouteUncovered,
+			Severity:   severity,
+			Route:      route.Path,
+			Message:    fmt.Sprintf("batch delete route %s is not covered by any pattern of module %s", route.Path, module),
+			Suggestion: "Add a pattern of the module to router source, or add the route to coverage allowlist if it is intentionally unprotected",
+		})
+	}
+
+	return
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+func Test_checkRouteCoverage(t *testing.T) {
+	routerSource := RouterSource{
+		UseMasterPattern("cities"): SourceRelation{Source: "city"},
+		"[invalid":                 SourceRelation{Source: "country"},
+	}
+
+	listDeleteRoute := []MappingRoute{
+		{Method: "DELETE", Path: "/api/v1/master/cities/:id"},
+		{Method: "DELETE", Path: "/api/v1/master/hotels/:id"},
+		{Method: "DELETE", Path: "/api/v1/master/logs/:id"},
+	}
+	listPostRoute := []MappingRoute{
+		{Method: "POST", Path: "/api/v1/master/batch-actions/delete/cities"},
+		{Method: "POST", Path: "/api/v1/master/batch-actions/delete/hotels"},
+		{Method: "POST", Path: "/api/v1/master/batch-actions/activate/hotels"},
+		{Method: "POST", Path: "/api/v1/master/batch-actions/:action/:module"},
+		{Method: "POST", Path: "/api/v1/master/cities"},
+	}
+
+	// Case 1: uncovered routes are warnings
+	issues := checkRouteCoverage(routerSource, listDeleteRoute, listPostRoute, CoverageConfig{})
+	routes := []string{}
+	for _, issue := range issues {
+		utils.AssertEqual(t, IssueRouteUncovered, issue.Code, "validate code")
+		utils.AssertEqual(t, SeverityWarning, issue.Severity, "validate severity")
+		routes = append(routes, issue.Route)
+	}
+	utils.AssertEqual(t, []string{
+		"/api/v1/master/hotels/:id",
+		"/api/v1/master/logs/:id",
+		"/api/v1/master/batch-actions/delete/hotels",
+	}, routes, "validate uncovered routes")
+
+	// Case 2: allowlist by exact route and regex, gaps are errors
+	issues = checkRouteCoverage(routerSource, listDeleteRoute, listPostRoute, CoverageConfig{
+		Allowlist:  []string{"/api/v1/master/logs/:id", ".*/hotels"},
+		IsGapError: true,
+	})
+	utils.AssertEqual(t, 1, len(issues), "validate allowlist")
+	utils.AssertEqual(t, "/api/v1/master/hotels/:id", issues[0].Route, "validate uncovered route")
+	utils.AssertEqual(t, SeverityError, issues[0].Severity, "validate gap error")
+
+	report := ValidationReport{}
+	report.add(issues...)
+	utils.AssertEqual(t, true, report.Err() != nil, "validate report err")
+}
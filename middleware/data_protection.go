@@ -11,11 +11,13 @@ import (
 	"net/url"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/iancoleman/strcase"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 type routerMap map[string]string
@@ -43,9 +45,48 @@ type SourceRelation struct {
 	Source           string   // ex: country
 	RequiredRelation []string // ex: city
 	IgnoreRelation   []string // ex: city_translation
+	ImmutableColumns []string // ex: currency_code, cannot be changed by PUT/PATCH once the source is referenced
+	Deactivate       *DeactivateRule
+	IgnoreActions    map[string]IgnoreAction // ex: {"city_translation": IgnoreCascade}, only used by table of IgnoreRelation
 }
 type RouterSource map[string]SourceRelation
 
+// DeactivateRule - deactivation of the source is protected like deletion
+type DeactivateRule struct {
+	Field        string      // ex: is_active, PUT/PATCH request body which deactivates the source
+	Value        interface{} // ex: false
+	BatchActions []string    // ex: deactivate, batch action verb next to delete, POST .../batch-actions/deactivate/cities
+
+	// Optional dependents which block deactivation, same rule as SourceRelation.
+	// If both are empty, relations of deletion are used.
+	RequiredRelation []string
+	IgnoreRelation   []string
+}
+
+// isDeactivating - request values deactivate the source, compared by data type of the field
+func (r *DeactivateRule) isDeactivating(dataTypes map[string]schema.DataType, requestValues map[string]interface{}) bool {
+	if r == nil || r.Field == "" {
+		return false
+	}
+
+	value, ok := requestValues[r.Field]
+	return ok && isSameValue(dataTypes[r.Field], r.Value, value)
+}
+
+// hasBatchAction - batch action verb deactivates the source
+func (r *DeactivateRule) hasBatchAction(action string) bool {
+	if r == nil {
+		return false
+	}
+
+	for _, batchAction := range r.BatchActions {
+		if batchAction == action {
+			return true
+		}
+	}
+	return false
+}
+
 func (rs *RouterSource) toRouterMaps(listRelationSchema []model.RelationSchema) (deleteRouteMaps routerMaps, err error) {
 	deleteRouteMaps = make(routerMaps)
 
@@ -116,6 +157,27 @@ func (rs *RouterSource) toRouterMaps(listRelationSchema []model.RelationSchema)
 	return
 }
 
+// toTableMaps - key router maps by source table instead of route pattern.
+// Routes sharing the same source are merged, so every relation of the source is protected.
+func (rs *RouterSource) toTableMaps(rMaps routerMaps) (tableMaps routerMaps) {
+	tableMaps = make(routerMaps)
+	for routePattern, rMap := range rMaps {
+		sourceRelation, ok := (*rs)[routePattern]
+		if !ok {
+			continue
+		}
+
+		// addMap update existing value, so router map of route pattern must be copied
+		newRouterMap := make(routerMap, len(rMap))
+		for tableName, fieldName := range rMap {
+			newRouterMap[tableName] = fieldName
+		}
+		tableMaps.addMap(sourceRelation.Source, newRouterMap)
+	}
+
+	return
+}
+
 // deleteRouteMaps
 // Format:
 //
@@ -146,15 +208,13 @@ func getListRelationSchema(db *gorm.DB) (listRelationSchema []model.RelationSche
 }
 
 // generateDeleteRouteMaps - map list relation schema to router maps
-func generateDeleteRouteMaps(db *gorm.DB) (deleteRouteMaps routerMaps, err error) {
+func generateDeleteRouteMaps(db *gorm.DB, listRouterSource RouterSource) (deleteRouteMaps routerMaps, err error) {
 	listRelationSchema, errGet := getListRelationSchema(db)
 	if errGet != nil {
 		err = errGet
 		return
 	}
 
-	listRouterSource := getDeleteRouterSource()
-
 	deleteRouteMaps, err = listRouterSource.toRouterMaps(listRelationSchema)
 	if err != nil {
 		return
@@ -163,47 +223,80 @@ func generateDeleteRouteMaps(db *gorm.DB) (deleteRouteMaps routerMaps, err error
 	return
 }
 
-// updateRouteMaps
+// generateDeactivateRouteMaps - router maps of route pattern which has deactivate rule.
+// Rule without its own relations uses the relations of delete router maps.
+func generateDeactivateRouteMaps(db *gorm.DB, listRouterSource RouterSource, deleteRouteMaps routerMaps) (deactivateRouteMaps routerMaps, err error) {
+	deactivateRouteMaps = make(routerMaps)
+
+	ruleRouterSource := make(RouterSource)
+	for routePattern, sourceRelation := range listRouterSource {
+		rule := sourceRelation.Deactivate
+		if rule == nil {
+			continue
+		}
+
+		if len(rule.RequiredRelation) == 0 && len(rule.IgnoreRelation) == 0 {
+			if rMap, ok := deleteRouteMaps[routePattern]; ok {
+				deactivateRouteMaps[routePattern] = rMap
+			}
+			continue
+		}
+
+		ruleRouterSource[routePattern] = SourceRelation{
+			Source:           sourceRelation.Source,
+			RequiredRelation: rule.RequiredRelation,
+			IgnoreRelation:   rule.IgnoreRelation,
+		}
+	}
+
+	if len(ruleRouterSource) == 0 {
+		return
+	}
+
+	ruleRouteMaps, err := generateDeleteRouteMaps(db, ruleRouterSource)
+	if err != nil {
+		return
+	}
+
+	for routePattern, rMap := range ruleRouteMaps {
+		deactivateRouteMaps[routePattern] = rMap
+	}
+	return
+}
+
+// toUpdateRouterMaps - router maps of route pattern which has immutable columns,
+// references are found by the same relations of delete router maps
 // Format:
 //
 //	"regex_pattern": {
 //	    "table_name": "id_field_name",
 //	    "table_name": "id_field_name",
 //	}
-var updateRouteMaps = routerMaps{}
-
-// generateDataProtectionQuery - count data based on table criteria
-func generateDataProtectionQuery(tables routerMap, ids []uuid.UUID) string {
-	queries := []string{}
-	// Argument indexes (simplify repeatable arguments)
-	// Source: https://faun.pub/golangs-fmt-sprintf-and-printf-demystified-4adf6f9722a2
-	queryTemplate := `
-	SELECT COUNT(*) total FROM "%[1]s" 
-	WHERE "%[1]s"."%[2]s" IN(%[3]s) AND "%[1]s"."deleted_at" IS NULL`
-	for tableName := range tables {
-		fieldName := tables[tableName]
-		strIds := lib.ConvertSliceUUIDToStr(ids, ",", `'%s'`)
-		queries = append(queries,
-			fmt.Sprintf(queryTemplate,
-				tableName, fieldName, strIds))
-	}
-
-	output := ""
-	if len(queries) > 0 {
-		output = fmt.Sprintf(
-			`SELECT SUM("s"."total") "total" FROM (
-				%s
-			) "s"`, strings.Join(queries, "\nUNION\n"))
-	}
-
-	return output
+func (rs *RouterSource) toUpdateRouterMaps(deleteRouteMaps routerMaps) (updateRouteMaps routerMaps) {
+	updateRouteMaps = make(routerMaps)
+	for routePattern, sourceRelation := range *rs {
+		if len(sourceRelation.ImmutableColumns) == 0 {
+			continue
+		}
+
+		if rMap, ok := deleteRouteMaps[routePattern]; ok {
+			updateRouteMaps[routePattern] = rMap
+		}
+	}
+
+	return
 }
 
 func isDeleteMethod(method string) bool {
 	return method == "DELETE"
 }
 
-func isDeleteBatchAction(c fiber.Ctx) (moduleName string, ids []uuid.UUID, isDeleteBatchAction bool, err error) {
+// batchActionRoutePattern - batch action route, capture action and module name, ex: .../batch-actions/delete/countries
+var batchActionRoutePattern = ".*/batch-actions?/([^/]+\\S)/([^/]+\\S)$"
+
+// isBatchAction - match batch action request of any action, ex: POST .../batch-actions/delete/countries.
+// Ids of request body are parsed by parseBatchIDs, only if the action is protected.
+func isBatchAction(c *fiber.Ctx) (action, moduleName string, isBatchAction bool, err error) {
 	// Validate method
 	method := c.Method()
 	if method != "POST" {
@@ -211,11 +304,9 @@ func isDeleteBatchAction(c fiber.Ctx) (moduleName string, ids []uuid.UUID, isDel
 	}
 
 	// Validate path
-	var batchActionRoutePattern = ".*/batch-actions?/([^/]+\\S)/([^/]+\\S)$"
-
 	pattern, err := regexp.Compile(batchActionRoutePattern)
 	if err != nil {
-		err = fmt.Errorf("isDeleteBatchAction: %s", err.Error())
+		err = fmt.Errorf("isBatchAction: %s", err.Error())
 		return
 	}
 
@@ -226,57 +317,26 @@ func isDeleteBatchAction(c fiber.Ctx) (moduleName string, ids []uuid.UUID, isDel
 	}
 
 	matches := pattern.FindStringSubmatch(route)
-	if len(matches) < 2 {
-		return
-	}
-
-	if matches[1] != "delete" {
+	if len(matches) < 3 {
 		return
 	}
 
-	ids = *new([]uuid.UUID)
-	if errParse := c.BodyParser(&ids); err != nil {
-		err = fmt.Errorf("isDeleteBatchAction: %s", errParse.Error())
-		return
-	}
-
-	isDeleteBatchAction = true
+	isBatchAction = true
+	action = matches[1]
 	moduleName = matches[2]
 
 	return
 }
 
-// matchingRouteToTables - map route with table sources
-func matchingRouteToTables(db *gorm.DB, route, method string) (*routerMap, *uuid.UUID, error) {
-	isDeleteMethod := isDeleteMethod(method)
-
-	var r *routerMap
-	var id *uuid.UUID
-	rMaps := updateRouteMaps
-	if isDeleteMethod {
-		maps, errMaps := generateDeleteRouteMaps(db)
-		if errMaps != nil {
-			return nil, nil, errMaps
-		}
-
-		rMaps = maps
-	}
-	for i := range rMaps {
-		pattern, err := regexp.Compile(i)
-		if nil == err && pattern.MatchString(route) {
-			result := pattern.FindStringSubmatch(route)
-			if len(result) > 0 {
-				idParam, err := uuid.Parse(result[1])
-				if nil == err && idParam != uuid.Nil {
-					rMap := rMaps[i]
-					id = &idParam
-					r = &rMap
-				}
-			}
-		}
+// parseBatchIDs - ids of batch action request body, ex: ["6f1c2a9e-3b4d-4e5f-8a7b-9c0d1e2f3a4b"]
+func parseBatchIDs(c *fiber.Ctx) (ids []uuid.UUID, err error) {
+	ids = *new([]uuid.UUID)
+	if errParse := c.BodyParser(&ids); errParse != nil {
+		err = fmt.Errorf("parseBatchIDs: %s", errParse.Error())
+		return
 	}
 
-	return r, id, nil
+	return
 }
 
 func getBatchActionModuleName(db *gorm.DB, name string) (string, error) {
@@ -313,117 +373,324 @@ func getBatchActionModuleName(db *gorm.DB, name string) (string, error) {
 
 }
 
-func matchBatchActionRouteTable(db *gorm.DB, moduleName string) (r *routerMap, err error) {
-	var deleteRouterSourcePattern = ".*/([a-z-]+)\\S+"
-
+func matchBatchActionRoute(db *gorm.DB, routes []compiledRoute, moduleName string) (r *compiledRoute, err error) {
 	formatModuleName, errFormat := getBatchActionModuleName(db, moduleName)
 	if errFormat != nil {
-		err = fmt.Errorf("matchBatchActionRouteTable: route module is invalid, %s", err.Error())
+		err = fmt.Errorf("matchBatchActionRoute: route module is invalid, %s", errFormat.Error())
 		return
 	}
 
-	rMaps, errGen := generateDeleteRouteMaps(db)
-	if errGen != nil {
-		err = errGen
-		return
+	for i := range routes {
+		if !lib.IsEmptyStr(routes[i].moduleName) && routes[i].moduleName == formatModuleName {
+			r = &routes[i]
+			break
+		}
 	}
 
-	for k, v := range rMaps {
-		pattern, err := regexp.Compile(deleteRouterSourcePattern)
-		if nil == err && pattern.MatchString(k) {
-			result := pattern.FindStringSubmatch(k)
-			if len(result) > 0 {
-				routerModule := (result[1])
-				formatRouterModule, err := getBatchActionModuleName(db, routerModule)
+	return
+}
 
-				if nil == err && formatRouterModule == formatModuleName {
-					// assign value
-					rMap := v
-					r = &rMap
-					break
-				}
-			}
+// getRouteModuleName - get batch action module name of route pattern, ex: .*/countries?/([^/]+)$ = country
+func getRouteModuleName(db *gorm.DB, routePattern string) (moduleName string) {
+	if routerModule := getRoutePatternModule(routePattern); !lib.IsEmptyStr(routerModule) {
+		formatRouterModule, err := getBatchActionModuleName(db, routerModule)
+		if nil == err {
+			moduleName = formatRouterModule
 		}
 	}
 
 	return
 }
 
-func validateProtectionQuery(db *gorm.DB, rmap routerMap, ids []uuid.UUID) (isAllowed bool) {
-	query := generateDataProtectionQuery(rmap, ids)
-	result := struct {
-		Total int64
-	}{}
-	if query != "" {
-		raw := db.Raw(query).Scan(&result)
-		if raw.RowsAffected > 0 && result.Total > 0 {
-			isAllowed = false
-			return
+// getRoutePatternModule - get module of route pattern as written on the route, ex: .*/countries?/([^/]+)$ = countries
+func getRoutePatternModule(routePattern string) (routerModule string) {
+	var deleteRouterSourcePattern = ".*/([a-z-]+)\\S+"
+
+	pattern, err := regexp.Compile(deleteRouterSourcePattern)
+	if nil == err && pattern.MatchString(routePattern) {
+		result := pattern.FindStringSubmatch(routePattern)
+		if len(result) > 1 {
+			routerModule = result[1]
 		}
 	}
 
-	isAllowed = true
 	return
 }
 
+// Decision - result of data protection on a request
+type Decision struct {
+	// IsProtected - request is matched with a protected route
+	IsProtected bool
+	// IsAllowed - request is allowed to be continued
+	IsAllowed bool
+	// Violations - dependent tables which are blocking the request, only if not allowed
+	Violations []Violation
+	// ChangedColumns - immutable columns changed by update request, only if update is not allowed
+	ChangedColumns []string
+	// Action - protected action of the request, ex: ActionDelete
+	Action string
+}
+
+// Protected action of a request
+const (
+	ActionDelete     = "delete"
+	ActionUpdate     = "update"
+	ActionDeactivate = "deactivate"
+)
+
+// deleteBatchAction - action of batch delete, ex: POST .../batch-actions/delete/countries
+const deleteBatchAction = "delete"
+
+// ViolationResponse - 405 response of blocked delete, with dependent tables to be cleaned up first
+type ViolationResponse struct {
+	lib.Response
+	Violations []Violation `json:"violations"`
+}
+
 // runDataProtection - middleware for protect specific data, map by router
-func runDataProtection(c *fiber.Ctx, db *gorm.DB) error {
-	errResp := initValidation()
-	if !errResp.IsEmpty() {
-		return errResp.SendToContext(c)
+func runDataProtection(c *fiber.Ctx, db *gorm.DB, loadRoutes routesLoader, cfg protectionConfig) error {
+	_, errRequest, err := checkDataProtection(c, db.WithContext(c.UserContext()), loadRoutes, cfg)
+
+	var errViolation *ProtectionViolationError
+	if errors.As(err, &errViolation) {
+		message := "Sorry, you are not allowed to delete this data. It is already used in transactions."
+		switch errViolation.Action {
+		case ActionUpdate:
+			message = fmt.Sprintf("Sorry, you are not allowed to change %s of this data. It is already used in transactions.",
+				strings.Join(errViolation.ChangedColumns, ", "))
+		case ActionDeactivate:
+			message = "Sorry, you are not allowed to deactivate this data. It is already used in transactions."
+		}
+
+		return c.Status(fiber.StatusMethodNotAllowed).JSON(ViolationResponse{
+			Response: lib.Response{
+				Status:  fiber.StatusMethodNotAllowed,
+				Message: message,
+			},
+			Violations: errViolation.Violations,
+		})
+	}
+
+	if err != nil {
+		return cfg.onFailure(c, err)
+	}
+
+	if errRequest != nil {
+		return errRequest.send(c)
+	}
+
+	return c.Next()
+}
+
+// checkDataProtection - decide whether request is allowed, without responding to the request.
+// err is *ProtectionViolationError if request is not allowed, or *ProtectionQueryError or *ProtectionLoadError,
+// returned if protection query or route maps are failed and failure policy is not FailOpen. errRequest is returned if request cannot be checked, ex: invalid request body.
+func checkDataProtection(c *fiber.Ctx, db *gorm.DB, loadRoutes routesLoader, cfg protectionConfig) (decision Decision, errRequest *RequestError, err error) {
+	if errResp := initValidation(); !errResp.IsEmpty() {
+		errRequest = &RequestError{StatusCode: errResp.Code(), Message: errResp.Description()}
+		return
 	}
 
 	var (
-		dataMap *routerMap
-		dataIds *[]uuid.UUID
+		checks []protectionCheck
+		routes compiledRouteMaps
 	)
 
-	moduleName, ids, isDeleteBatchAction, err := isDeleteBatchAction(*c)
-	if err != nil {
-		log.Println("ERROR failed validate request batch 1:", err.Error())
-		return lib.ErrorInternal(c, "failed validate request batch 1")
+	action, moduleName, isBatchAction, errBatch := isBatchAction(c)
+	if errBatch != nil {
+		log.Println("ERROR failed validate request batch 1:", errBatch.Error())
+		errRequest = &RequestError{StatusCode: fiber.StatusInternalServerError, Message: "failed validate request batch 1", Err: errBatch}
+		return
 	}
 
-	if isDeleteBatchAction {
-		rmap, err := matchBatchActionRouteTable(db, moduleName)
-		if err != nil {
-			log.Println("ERROR failed validate request batch 2:", err.Error())
-			return lib.ErrorBadRequest(c, "failed validate request batch 2, invalid path module name")
+	if isBatchAction {
+		var errLoad error
+		routes, errLoad = loadRoutes()
+		if errLoad != nil {
+			decision.IsAllowed, err = cfg.onLoadFailure(errLoad)
+			return
 		}
 
-		if nil != rmap && len(ids) > 0 {
-			dataMap = rmap
-			dataIds = &ids
+		// Other than delete, only deactivate batch action of router source is protected
+		batchRoutes, checkAction := routes.deleteRoutes, ActionDelete
+		if action != deleteBatchAction {
+			batchRoutes, checkAction = filterDeactivateBatchRoutes(routes.deactivateRoutes, action), ActionDeactivate
+		}
+
+		if len(batchRoutes) > 0 {
+			route, errMatch := matchBatchActionRoute(db, batchRoutes, moduleName)
+			if errMatch != nil {
+				log.Println("ERROR failed validate request batch 2:", errMatch.Error())
+				errRequest = &RequestError{StatusCode: fiber.StatusBadRequest, Message: "failed validate request batch 2, invalid path module name", Err: errMatch}
+				return
+			}
+
+			// Body is only parsed for delete or deactivate batch action of router source
+			if nil != route {
+				ids, errParse := parseBatchIDs(c)
+				if errParse != nil {
+					log.Println("ERROR failed validate request batch 3:", errParse.Error())
+					errRequest = &RequestError{StatusCode: fiber.StatusBadRequest, Message: "failed validate request batch 3, invalid request body", Err: errParse}
+					return
+				}
+
+				if len(ids) > 0 {
+					checks = append(checks, protectionCheck{action: checkAction, table: route.source, source: route.source, rmap: route.tables, ids: ids})
+				}
+			}
 		}
 
 	} else {
 
 		isDeleteMethod := isDeleteMethod(c.Method())
-		if isDeleteMethod { // || c.Method() == "UPDATE" {
-			if rmap, id, err := matchingRouteToTables(db, c.Path(), c.Method()); err != nil {
-				log.Println("ERROR failed validate request delete:", err.Error())
-				return lib.ErrorInternal(c, "failed validate request delete")
-
-			} else if nil != rmap && !lib.IsEmptyUUIDPtr(id) {
-				dataMap = rmap
-				dataIds = &[]uuid.UUID{*id}
+		if isDeleteMethod {
+			var errLoad error
+			routes, errLoad = loadRoutes()
+			if errLoad != nil {
+				decision.IsAllowed, err = cfg.onLoadFailure(errLoad)
+				return
+			}
+
+			if route, id := matchingRoute(routes.deleteRoutes, c.Path()); nil != route && !lib.IsEmptyUUIDPtr(id) {
+				checks = append(checks, protectionCheck{action: ActionDelete, table: route.source, source: route.source, rmap: route.tables, ids: []uuid.UUID{*id}})
+			}
+		} else if isUpdateMethod(c.Method()) {
+			var errLoad error
+			routes, errLoad = loadRoutes()
+			if errLoad != nil {
+				decision.IsAllowed, err = cfg.onLoadFailure(errLoad)
+				return
+			}
+
+			checks, errRequest, err = getUpdateChecks(c, db, routes, cfg)
+			if errRequest != nil || err != nil {
+				return
 			}
 		}
 	}
 
-	if dataMap != nil && dataIds != nil {
-		if isAllowed := validateProtectionQuery(db, *dataMap, *dataIds); !isAllowed {
-			return lib.ErrorNotAllowed(c,
-				"Sorry, you are not allowed to delete this data. It is already used in transactions.")
+	// Stop at the first check which is not allowed
+	decision.IsAllowed = true
+	for _, check := range checks {
+		decision, err = decideDelete(db, routes, check.source, check.rmap, check.ids, cfg)
+		decision.Action = check.action
+		if err != nil {
+			return
+		}
+		if !decision.IsAllowed {
+			decision.ChangedColumns = check.changedColumns
+			err = newViolationError(check.table, check.ids, decision)
+			return
 		}
 	}
 
-	return c.Next()
+	return
+}
+
+// protectionCheck - ids of a request which are protected by router map
+type protectionCheck struct {
+	action         string
+	table          string
+	source         string // only used by delete and deactivate, for transitive check
+	rmap           routerMap
+	ids            []uuid.UUID
+	changedColumns []string // only used by update
+}
+
+// getUpdateChecks - checks of PUT/PATCH request, deactivation is checked before immutable columns.
+// Failed query of stored row follows failure policy, FailOpen skips the immutable column check.
+func getUpdateChecks(c *fiber.Ctx, db *gorm.DB, routes compiledRouteMaps, cfg protectionConfig) (checks []protectionCheck, errRequest *RequestError, err error) {
+	deactivateRoute, deactivateID := matchingRoute(filterDeactivateFieldRoutes(routes.deactivateRoutes), c.Path())
+	isDeactivate := nil != deactivateRoute && !lib.IsEmptyUUIDPtr(deactivateID)
+
+	updateRoute, updateID := matchingRoute(routes.updateRoutes, c.Path())
+	isUpdate := nil != updateRoute && !lib.IsEmptyUUIDPtr(updateID)
+
+	if !isDeactivate && !isUpdate {
+		return
+	}
+
+	requestValues, errBody := parseRequestValues(c)
+	if errBody != nil {
+		log.Println("ERROR failed validate request update:", errBody.Error())
+		errRequest = &RequestError{StatusCode: fiber.StatusBadRequest, Message: "failed validate request update, invalid request body", Err: errBody}
+		return
+	}
+
+	if isDeactivate && deactivateRoute.deactivate.isDeactivating(routes.fieldTypes[deactivateRoute.source], requestValues) {
+		checks = append(checks, protectionCheck{action: ActionDeactivate, table: deactivateRoute.source, source: deactivateRoute.source, rmap: deactivateRoute.tables, ids: []uuid.UUID{*deactivateID}})
+	}
+
+	if isUpdate {
+		changedColumns, errChanged := findChangedColumns(db, routes.schemas, routes.fieldTypes[updateRoute.source], *updateRoute, *updateID, requestValues)
+		if errChanged != nil {
+			log.Println("ERROR findChangedColumns:", errChanged.Error())
+
+			if cfg.failurePolicy != FailOpen {
+				err = errChanged
+				return
+			}
+			log.Println("WARNING findChangedColumns: fail open, immutable columns are allowed to be updated without protection")
+		}
+
+		if len(changedColumns) > 0 {
+			checks = append(checks, protectionCheck{action: ActionUpdate, table: updateRoute.source, rmap: updateRoute.tables, ids: []uuid.UUID{*updateID}, changedColumns: changedColumns})
+		}
+	}
+
+	return
+}
+
+// canDelete - decide whether ids of source table are allowed to be deleted, without HTTP request.
+// Table which is not a source of router source is not protected.
+func canDelete(db *gorm.DB, loadRoutes routesLoader, cfg protectionConfig, tableName string, ids []uuid.UUID) (decision Decision, err error) {
+	decision.IsAllowed = true
+	if len(ids) == 0 {
+		return
+	}
+
+	routes, errLoad := loadRoutes()
+	if errLoad != nil {
+		decision.IsAllowed, err = cfg.onLoadFailure(errLoad)
+		return
+	}
+
+	rmap, ok := routes.isProtectedTable(tableName, cfg)
+	if !ok {
+		return
+	}
+
+	decision, err = decideDelete(db, routes, tableName, rmap, ids, cfg)
+	if err == nil && !decision.IsAllowed {
+		err = newViolationError(tableName, ids, decision)
+	}
+
+	return
+}
+
+// decideDelete - protection decision of ids which are protected by router map.
+// In transitive mode, delete of source is checked through ignored and cascaded relations too.
+func decideDelete(db *gorm.DB, routes compiledRouteMaps, source string, rmap routerMap, ids []uuid.UUID, cfg protectionConfig) (decision Decision, err error) {
+	decision.IsProtected = true
+	decision.Action = ActionDelete
+	decision.IsAllowed, decision.Violations, err = validateProtectionQuery(db, routes.schemas, rmap, ids, cfg)
+	if err != nil || !decision.IsAllowed || !cfg.isTransitive || source == "" {
+		return
+	}
+
+	decision.IsAllowed, decision.Violations, err = validateTransitiveQuery(db, routes.dependencyGraph, routes.schemas, source, ids, cfg)
+	return
 }
 
-var isInit bool = true
+var (
+	isInit      bool = true
+	isInitMutex sync.Mutex
+)
 
 func initValidation() (errResp lib.ErrorResponse) {
+	isInitMutex.Lock()
+	defer isInitMutex.Unlock()
+
 	// Only validate one time
 	if !isInit {
 		return
@@ -0,0 +1,221 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// compiledRoute - route pattern which is compiled once, with its router map
+type compiledRoute struct {
+	pattern          string
+	regex            *regexp.Regexp
+	tables           routerMap
+	moduleName       string          // batch action module name, ex: country
+	source           string          // source table, ex: country
+	immutableColumns []string        // only used by update route
+	deactivate       *DeactivateRule // only used by deactivate route
+}
+
+// compiledRouteMaps - effective route maps of a middleware
+type compiledRouteMaps struct {
+	deleteRoutes     []compiledRoute
+	updateRoutes     []compiledRoute
+	deactivateRoutes []compiledRoute
+	deleteTables     routerMaps                   // delete router maps keyed by source table, ex: country
+	ignoredTables    map[string][]ignoredRelation // ignored relations with action keyed by source table
+	dependencyGraph  dependencyGraph              // whole relation schema keyed by referenced table, for transitive check
+	schemas          modelSchemas
+	fieldTypes       modelFieldTypes // data type of columns, to compare stored value with request value
+}
+
+// routesLoader - lazy load compiled route maps, only called if request must be protected
+type routesLoader func() (routes compiledRouteMaps, err error)
+
+// routeMapsCache - in memory cache of compiled route maps.
+// Invalidated on demand by Refresh, after MigrateRelation succeed, or when router source is changed.
+type routeMapsCache struct {
+	mutex      sync.RWMutex
+	isValid    bool
+	routes     compiledRouteMaps
+	schemas    modelSchemas
+	fieldTypes modelFieldTypes
+}
+
+func newRouteMapsCache() *routeMapsCache {
+	return new(routeMapsCache)
+}
+
+// get - get compiled route maps, build it first if cache is invalid
+func (r *routeMapsCache) get(db *gorm.DB, registry *routerRegistry) (routes compiledRouteMaps, err error) {
+	r.mutex.RLock()
+	if r.isValid {
+		routes = r.routes
+		r.mutex.RUnlock()
+		return
+	}
+	r.mutex.RUnlock()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Other request may have built the cache
+	if r.isValid {
+		routes = r.routes
+		return
+	}
+
+	routes, err = compileRouteMaps(db, registry.get(), r.schemas)
+	if err != nil {
+		return
+	}
+	routes.fieldTypes = r.fieldTypes
+
+	r.routes = routes
+	r.isValid = true
+	return
+}
+
+// loader - make routesLoader of this cache
+func (r *routeMapsCache) loader(db *gorm.DB, registry *routerRegistry) routesLoader {
+	return func() (compiledRouteMaps, error) {
+		return r.get(db, registry)
+	}
+}
+
+// invalidate - compiled route maps will be rebuilt on the next protected request
+func (r *routeMapsCache) invalidate() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.isValid = false
+	r.routes = compiledRouteMaps{}
+}
+
+// setSchemas - set model schemas to validate relation schema, then invalidate the cache
+func (r *routeMapsCache) setSchemas(schemas modelSchemas, fieldTypes modelFieldTypes) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.schemas = schemas
+	r.fieldTypes = fieldTypes
+	r.isValid = false
+	r.routes = compiledRouteMaps{}
+}
+
+// compileRouteMaps - generate delete route maps from relation schema, then compile all patterns
+func compileRouteMaps(db *gorm.DB, routerSource RouterSource, schemas modelSchemas) (routes compiledRouteMaps, err error) {
+	deleteRouteMaps, err := generateDeleteRouteMaps(db, routerSource)
+	if err != nil {
+		return
+	}
+
+	// Table and column from relation_schema will be used as query identifier
+	updateRouteMaps := routerSource.toUpdateRouterMaps(deleteRouteMaps)
+	deactivateRouteMaps, err := generateDeactivateRouteMaps(db, routerSource, deleteRouteMaps)
+	if err != nil {
+		return
+	}
+
+	for _, rMaps := range []routerMaps{deleteRouteMaps, updateRouteMaps, deactivateRouteMaps} {
+		if err = schemas.validateRouterMaps(rMaps); err != nil {
+			log.Println("ERROR compileRouteMaps:", err.Error())
+			return
+		}
+	}
+
+	// Immutable columns and ignored relations will be used as query identifier too
+	if err = schemas.validateImmutableColumns(routerSource); err != nil {
+		log.Println("ERROR compileRouteMaps:", err.Error())
+		return
+	}
+
+	ignoredTables, err := generateIgnoredRelations(db, routerSource)
+	if err != nil {
+		return
+	}
+	if err = schemas.validateIgnoredRelations(ignoredTables); err != nil {
+		log.Println("ERROR compileRouteMaps:", err.Error())
+		return
+	}
+	routes.ignoredTables = ignoredTables
+	routes.schemas = schemas
+
+	routes.deleteRoutes, err = compileRoutes(db, routerSource, deleteRouteMaps)
+	if err != nil {
+		return
+	}
+	routes.deleteTables = routerSource.toTableMaps(deleteRouteMaps)
+
+	routes.dependencyGraph, err = generateDependencyGraph(db, routerSource, routes.deleteTables, ignoredTables)
+	if err != nil {
+		return
+	}
+
+	routes.updateRoutes, err = compileRoutes(db, routerSource, updateRouteMaps)
+	if err != nil {
+		return
+	}
+
+	routes.deactivateRoutes, err = compileRoutes(db, routerSource, deactivateRouteMaps)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func compileRoutes(db *gorm.DB, routerSource RouterSource, rMaps routerMaps) (routes []compiledRoute, err error) {
+	for routePattern, rMap := range rMaps {
+		pattern, errCompile := regexp.Compile(routePattern)
+		if errCompile != nil {
+			log.Println("ERROR compileRoutes:", errCompile.Error())
+			err = fmt.Errorf("failed compile route pattern: %s, message: %s", routePattern, errCompile.Error())
+			return
+		}
+
+		routes = append(routes, compiledRoute{
+			pattern:          routePattern,
+			regex:            pattern,
+			tables:           rMap,
+			moduleName:       getRouteModuleName(db, routePattern),
+			source:           routerSource[routePattern].Source,
+			immutableColumns: routerSource[routePattern].ImmutableColumns,
+			deactivate:       routerSource[routePattern].Deactivate,
+		})
+	}
+
+	return
+}
+
+// isProtectedTable - delete of table must be checked, source without router map is only checked by transitive check
+func (routes compiledRouteMaps) isProtectedTable(tableName string, cfg protectionConfig) (rmap routerMap, isProtected bool) {
+	rmap, isProtected = routes.deleteTables[tableName]
+	if !isProtected && cfg.isTransitive {
+		isProtected = routes.dependencyGraph[tableName].isSource
+	}
+	return
+}
+
+// filterDeactivateFieldRoutes - deactivate routes which are deactivated by request body
+func filterDeactivateFieldRoutes(routes []compiledRoute) (filtered []compiledRoute) {
+	for i := range routes {
+		if routes[i].deactivate != nil && routes[i].deactivate.Field != "" {
+			filtered = append(filtered, routes[i])
+		}
+	}
+	return
+}
+
+// filterDeactivateBatchRoutes - deactivate routes which are deactivated by batch action
+func filterDeactivateBatchRoutes(routes []compiledRoute, action string) (filtered []compiledRoute) {
+	for i := range routes {
+		if routes[i].deactivate.hasBatchAction(action) {
+			filtered = append(filtered, routes[i])
+		}
+	}
+	return
+}
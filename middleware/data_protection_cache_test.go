@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/terra-discover/bbcrs-helper-lib/pkg/lib"
+	standardModel "github.com/terra-discover/bbcrs-migration-lib/model"
+)
+
+func Test_routeMapsCache_get(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	registry := newRouterRegistry()
+	registry.merge(RouterSource{
+		UseMasterPattern("countries"): SourceRelation{
+			Source: "country",
+		},
+	})
+	cache := newRouteMapsCache()
+	schemas, fieldTypes, err := parseModelSchemas(db, testSupport__GetModelMigrations())
+	utils.AssertEqual(t, nil, err, "parse model schemas")
+	cache.setSchemas(schemas, fieldTypes)
+
+	// Case 1: relation schema empty, no route maps
+	routes, err := cache.get(db, registry)
+	utils.AssertEqual(t, nil, err, "validate err")
+	utils.AssertEqual(t, 0, len(routes.deleteRoutes), "validate delete routes")
+	utils.AssertEqual(t, true, cache.isValid, "validate cache")
+
+	// Case 2: new relation schema is not loaded before invalidated
+	relationSchema := standardModel.RelationSchema{
+		ColumnSource: lib.Strptr("id"),
+		TableSource:  lib.Strptr("country"),
+		UsedByColumn: lib.Strptr("country_id"),
+		UsedByTable:  lib.Strptr("city"),
+	}
+	err = db.Create(&relationSchema).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	routes, err = cache.get(db, registry)
+	utils.AssertEqual(t, nil, err, "validate err")
+	utils.AssertEqual(t, 0, len(routes.deleteRoutes), "validate cached delete routes")
+
+	// Case 3: invalidated, relation schema is reloaded and pattern is compiled
+	cache.invalidate()
+	routes, err = cache.get(db, registry)
+	utils.AssertEqual(t, nil, err, "validate err")
+	utils.AssertEqual(t, 1, len(routes.deleteRoutes), "validate delete routes")
+	utils.AssertEqual(t, true, routes.deleteRoutes[0].regex.MatchString("/api/v1/master/countries/1"), "validate compiled pattern")
+	utils.AssertEqual(t, "country", routes.deleteRoutes[0].moduleName, "validate module name")
+	utils.AssertEqual(t, "country_id", routes.deleteRoutes[0].tables["city"], "validate tables")
+
+	// Case 4: relation schema not listed on model migrations, error
+	cache.setSchemas(modelSchemas{"country": {"id": true}}, nil)
+	_, err = cache.get(db, registry)
+	utils.AssertEqual(t, true, err != nil, "validate err")
+	utils.AssertEqual(t, false, cache.isValid, "validate cache")
+
+	cache.setSchemas(schemas, fieldTypes)
+	_, err = cache.get(db, registry)
+	utils.AssertEqual(t, nil, err, "validate err")
+
+	// Case 5: db closed, cache still used
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+
+	routes, err = cache.get(db, registry)
+	utils.AssertEqual(t, nil, err, "validate err")
+	utils.AssertEqual(t, 1, len(routes.deleteRoutes), "validate cached delete routes")
+
+	// Case 6: db closed and invalidated, error
+	cache.invalidate()
+	_, err = cache.get(db, registry)
+	utils.AssertEqual(t, true, err != nil, "validate err")
+	utils.AssertEqual(t, false, cache.isValid, "validate cache")
+}
+
+func Test_compileRoutes(t *testing.T) {
+	// Case 1: invalid pattern, error
+	_, err := compileRoutes(nil, RouterSource{}, routerMaps{
+		"/countries/([^/]+$": routerMap{"city": "country_id"},
+	})
+	utils.AssertEqual(t, true, err != nil, "validate err")
+
+	// Case 2: empty route maps, not error
+	routes, err := compileRoutes(nil, RouterSource{}, routerMaps{})
+	utils.AssertEqual(t, nil, err, "validate err")
+	utils.AssertEqual(t, 0, len(routes), "validate routes")
+}
@@ -4,12 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sync"
 
+	"github.com/gofiber/fiber/v2"
 	"github.com/terra-discover/bbcrs-helper-lib/pkg/lib"
 )
 
 /*
-deleteRouterSource
+routerRegistry - registry of RouterSource, owned by each Middleware
 Format:
 
 	"regex_pattern": sourceRelation{
@@ -44,24 +46,41 @@ routerSource = the main table will be deleted by path :id, if delete action on r
 
 Note: If RequiredRelation and IgnoreRelation are declared, data protection ONLY validate RequiredRelation
 */
-var deleteRouterSource = RouterSource{}
+type routerRegistry struct {
+	mutex        sync.RWMutex
+	routerSource RouterSource
+}
+
+func newRouterRegistry() *routerRegistry {
+	return &routerRegistry{
+		routerSource: RouterSource{},
+	}
+}
+
+// get - get copy of router source, safe for concurrent requests
+func (r *routerRegistry) get() (rs RouterSource) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
 
-func getDeleteRouterSource() (r RouterSource) {
-	r = deleteRouterSource
+	rs = make(RouterSource, len(r.routerSource))
+	for k, v := range r.routerSource {
+		rs[k] = v
+	}
 	return
 }
 
-func setDeleteRouterSource(newRouterSource RouterSource) {
+// merge - add new router source, existing pattern will not be replaced
+func (r *routerRegistry) merge(newRouterSource RouterSource) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
 	for k, v := range newRouterSource {
-		if lib.IsEmptyStr(k) {
-			continue
-		}
-		if lib.IsEmptyStr(v.Source) {
+		if !isValidRouterSource(k, v) {
 			continue
 		}
 
-		if existValue, ok := deleteRouterSource[k]; !ok {
-			deleteRouterSource[k] = v
+		if existValue, ok := r.routerSource[k]; !ok {
+			r.routerSource[k] = v
 		} else {
 			byteVal, _ := json.MarshalIndent(existValue, "", " ")
 			log.Printf("exist deleteRouterSource[%s] = %s", k, string(byteVal))
@@ -69,6 +88,113 @@ func setDeleteRouterSource(newRouterSource RouterSource) {
 	}
 }
 
+// replace - add new router source, existing pattern will be replaced
+func (r *routerRegistry) replace(newRouterSource RouterSource) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for k, v := range newRouterSource {
+		if !isValidRouterSource(k, v) {
+			continue
+		}
+
+		r.routerSource[k] = v
+	}
+}
+
+// remove - remove router source by patterns
+func (r *routerRegistry) remove(patterns ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, pattern := range patterns {
+		delete(r.routerSource, pattern)
+	}
+}
+
+// reset - remove all router source
+func (r *routerRegistry) reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.routerSource = RouterSource{}
+}
+
+func isValidRouterSource(pattern string, sourceRelation SourceRelation) bool {
+	return !lib.IsEmptyStr(pattern) && !lib.IsEmptyStr(sourceRelation.Source)
+}
+
+// protectionConfig - options of data protection, owned by each Middleware
+type protectionConfig struct {
+	// probeConcurrency - maximum tables probed in parallel for one request, <= 1 means sequential
+	probeConcurrency int
+	// violationSampleSize - maximum referencing ids of each violation in 405 response, <= 0 means none
+	violationSampleSize int
+	// tenantColumn - column of tenant in dependent tables, ex: agent_id. Empty means tenancy mode is disabled
+	tenantColumn string
+	// tenantResolver - resolve tenant of HTTP request
+	tenantResolver TenantResolver
+	// tenantID - tenant of the current check, resolved per request
+	tenantID string
+	// failurePolicy - what to do with the request when protection query is failed
+	failurePolicy FailurePolicy
+	// failureCallback - only used by FailCallback
+	failureCallback FailureCallback
+	// isTransitive - walk ignored and cascaded relations of delete, default only one level is checked
+	isTransitive bool
+	// coverage - coverage analysis of routes, only used by MappingRoute
+	coverage CoverageConfig
+}
+
+// FailurePolicy - decide what to do with the request when protection query is failed,
+// ex: database timeout or dependent table is missing
+type FailurePolicy int
+
+const (
+	// FailClosed - reject the request with 503 Service Unavailable
+	FailClosed FailurePolicy = iota
+	// FailClosedInternal - reject the request with 500 Internal Server Error
+	FailClosedInternal
+	// FailOpen - log a warning and allow the request
+	FailOpen
+	// FailCallback - defer to FailureCallback
+	FailCallback
+)
+
+// FailureCallback - handle the request when protection query is failed, err is *ProtectionQueryError,
+// or *ProtectionLoadError if route maps cannot be loaded
+type FailureCallback func(c *fiber.Ctx, err error) error
+
+// onFailure - respond the failed request by failure policy
+func (cfg protectionConfig) onFailure(c *fiber.Ctx, err error) error {
+	switch cfg.failurePolicy {
+	case FailClosedInternal:
+		return lib.ErrorInternal(c, "failed validate request delete")
+	case FailCallback:
+		if cfg.failureCallback != nil {
+			return cfg.failureCallback(c, err)
+		}
+	}
+
+	return lib.Send(c, fiber.StatusServiceUnavailable,
+		"Sorry, data protection is temporarily unavailable. Please try again later.")
+}
+
+// onLoadFailure - route maps cannot be loaded, the result follows failure policy like failed protection query.
+// err is *ProtectionLoadError if failure policy is not FailOpen.
+func (cfg protectionConfig) onLoadFailure(errLoad error) (isAllowed bool, err error) {
+	log.Println("ERROR loadRoutes:", errLoad.Error())
+
+	if cfg.failurePolicy == FailOpen {
+		log.Println("WARNING loadRoutes: fail open, request is allowed without protection")
+		isAllowed = true
+		return
+	}
+
+	err = &ProtectionLoadError{Err: errLoad}
+	return
+}
+
 // Service prefix endpoint
 const (
 	masterServiceEndpoint      string = "/api/v1/master"
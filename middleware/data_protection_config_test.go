@@ -53,14 +53,16 @@ func Test_usePattern(t *testing.T) {
 	}
 }
 
-func Test_setDeleteRouterSource(t *testing.T) {
+func Test_routerRegistry_merge(t *testing.T) {
+	registry := newRouterRegistry()
+
 	existKey := UseMasterPattern("countries")
 	existVal := SourceRelation{
 		Source:         "country",
 		IgnoreRelation: []string{"country_translation"},
 	}
 
-	deleteRouterSource[existKey] = existVal
+	registry.routerSource[existKey] = existVal
 
 	type args struct {
 		newRouterSource RouterSource
@@ -119,7 +121,7 @@ func Test_setDeleteRouterSource(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			setDeleteRouterSource(tt.args.newRouterSource)
+			registry.merge(tt.args.newRouterSource)
 
 			// get only 1 args key, because filling maximum 1 key in this test
 			argsKey := ""
@@ -127,7 +129,7 @@ func Test_setDeleteRouterSource(t *testing.T) {
 				argsKey = k
 			}
 
-			val, ok := deleteRouterSource[argsKey]
+			val, ok := registry.routerSource[argsKey]
 			utils.AssertEqual(t, !lib.IsEmptyStr(tt.wantKey), ok, "validate key")
 			utils.AssertEqual(t, tt.wantVal.Source, val.Source, "validate source")
 			utils.AssertEqual(t, len(tt.wantVal.IgnoreRelation), len(val.IgnoreRelation), "valdate ignore relation")
@@ -135,3 +137,89 @@ func Test_setDeleteRouterSource(t *testing.T) {
 		})
 	}
 }
+
+func Test_routerRegistry_replace(t *testing.T) {
+	registry := newRouterRegistry()
+	registry.merge(RouterSource{
+		UseMasterPattern("countries"): SourceRelation{
+			Source:         "country",
+			IgnoreRelation: []string{"country_translation"},
+		},
+	})
+
+	// Case 1: existing pattern is replaced
+	registry.replace(RouterSource{
+		UseMasterPattern("countries"): SourceRelation{
+			Source: "country",
+		},
+		UseMasterPattern("cities"): SourceRelation{
+			Source: "city",
+		},
+	})
+
+	rs := registry.get()
+	utils.AssertEqual(t, 2, len(rs), "validate length")
+	utils.AssertEqual(t, 0, len(rs[UseMasterPattern("countries")].IgnoreRelation), "validate replaced value")
+	utils.AssertEqual(t, "city", rs[UseMasterPattern("cities")].Source, "validate new value")
+
+	// Case 2: invalid router source is not saved
+	registry.replace(RouterSource{
+		UseMasterPattern("countries"): SourceRelation{},
+	})
+
+	rs = registry.get()
+	utils.AssertEqual(t, "country", rs[UseMasterPattern("countries")].Source, "validate not replaced")
+}
+
+func Test_routerRegistry_remove(t *testing.T) {
+	registry := newRouterRegistry()
+	registry.merge(RouterSource{
+		UseMasterPattern("countries"): SourceRelation{
+			Source: "country",
+		},
+		UseMasterPattern("cities"): SourceRelation{
+			Source: "city",
+		},
+	})
+
+	registry.remove(UseMasterPattern("countries"), UseMasterPattern("not-exists"))
+
+	rs := registry.get()
+	utils.AssertEqual(t, 1, len(rs), "validate length")
+	_, ok := rs[UseMasterPattern("countries")]
+	utils.AssertEqual(t, false, ok, "validate removed key")
+}
+
+func Test_routerRegistry_reset(t *testing.T) {
+	registry := newRouterRegistry()
+	registry.merge(RouterSource{
+		UseMasterPattern("countries"): SourceRelation{
+			Source: "country",
+		},
+	})
+
+	registry.reset()
+	utils.AssertEqual(t, 0, len(registry.get()), "validate length")
+
+	// Registry still usable after reset
+	registry.merge(RouterSource{
+		UseMasterPattern("cities"): SourceRelation{
+			Source: "city",
+		},
+	})
+	utils.AssertEqual(t, 1, len(registry.get()), "validate length")
+}
+
+func Test_routerRegistry_isolated(t *testing.T) {
+	m1 := NewMiddleware(Environment{}, nil)
+	m2 := NewMiddleware(Environment{}, nil)
+
+	m1.ReplaceRouterSource(RouterSource{
+		UseMasterPattern("countries"): SourceRelation{
+			Source: "country",
+		},
+	})
+
+	utils.AssertEqual(t, false, m1.isRouterSourceEmpty(), "validate first instance")
+	utils.AssertEqual(t, true, m2.isRouterSourceEmpty(), "validate second instance")
+}
@@ -0,0 +1,283 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// generateReferenceCondition - FROM and WHERE clause of live rows of table which are referencing ids.
+// Identifiers are quoted by dialector of db, ids and tenant are bound by vars.
+// In tenancy mode, only rows of the tenant are referencing ids if table has tenant column.
+func generateReferenceCondition(db *gorm.DB, schemas modelSchemas, cfg protectionConfig, tableName, fieldName string, ids []uuid.UUID) (from, where string, vars []interface{}, err error) {
+	if err = schemas.validate(tableName, fieldName); err != nil {
+		return
+	}
+
+	stmt := db.Statement
+	from = stmt.Quote(clause.Table{Name: tableName})
+	where = fmt.Sprintf(`%s IN ?`, stmt.Quote(clause.Column{Table: tableName, Name: fieldName}))
+	vars = []interface{}{ids}
+
+	// Only live row must be counted
+	if schemas.hasColumn(tableName, softDeleteColumn) {
+		deletedAt := stmt.Quote(clause.Column{Table: tableName, Name: softDeleteColumn})
+		where += fmt.Sprintf(` AND %s IS NULL`, deletedAt)
+	}
+
+	if tenantColumn, ok := cfg.tenantColumnOf(schemas, tableName); ok {
+		tenant := stmt.Quote(clause.Column{Table: tableName, Name: tenantColumn})
+		where += fmt.Sprintf(` AND %s = ?`, tenant)
+		vars = append(vars, cfg.tenantID)
+	}
+
+	return
+}
+
+// generateDataProtectionQuery - find one live row of table which is referencing ids.
+// Database can stop scanning at the first referencing row.
+func generateDataProtectionQuery(db *gorm.DB, schemas modelSchemas, cfg protectionConfig, tableName, fieldName string, ids []uuid.UUID) (query string, vars []interface{}, err error) {
+	from, where, vars, err := generateReferenceCondition(db, schemas, cfg, tableName, fieldName, ids)
+	if err != nil {
+		return
+	}
+
+	query = fmt.Sprintf(`SELECT 1 AS %s FROM %s WHERE %s LIMIT 1`, db.Statement.Quote("found"), from, where)
+	return
+}
+
+// probeReference - check whether table still has a live row referencing ids
+func probeReference(db *gorm.DB, schemas modelSchemas, cfg protectionConfig, tableName, fieldName string, ids []uuid.UUID) (isFound bool, err error) {
+	query, vars, errQuery := generateDataProtectionQuery(db, schemas, cfg, tableName, fieldName, ids)
+	if errQuery != nil {
+		err = &ProtectionQueryError{Table: tableName, Column: fieldName, Err: errQuery}
+		return
+	}
+
+	result := []struct {
+		Found int
+	}{}
+
+	raw := db.Raw(query, vars...).Scan(&result)
+	if raw.Error != nil {
+		err = &ProtectionQueryError{Table: tableName, Column: fieldName, Err: raw.Error}
+		return
+	}
+
+	isFound = len(result) > 0
+	return
+}
+
+// findReference - probe every table of router map, stop at the first table which is referencing ids.
+// Tables are probed in parallel if probe concurrency > 1, except inside a transaction.
+func findReference(db *gorm.DB, schemas modelSchemas, rmap routerMap, ids []uuid.UUID, cfg protectionConfig) (isFound bool, err error) {
+	if len(rmap) == 0 || len(ids) == 0 {
+		return
+	}
+
+	// Sort tables, so sequential probe is deterministic
+	listTable := make([]string, 0, len(rmap))
+	for tableName := range rmap {
+		listTable = append(listTable, tableName)
+	}
+	sort.Strings(listTable)
+
+	// Statements of a transaction queue on its connection, and a cancelled statement aborts the transaction
+	// on some databases, ex: postgres. So tables are probed one by one inside a transaction.
+	concurrency := cfg.probeConcurrency
+	if concurrency <= 1 || isInTransaction(db) {
+		for _, tableName := range listTable {
+			isFound, err = probeReference(db, schemas, cfg, tableName, rmap[tableName], ids)
+			if err != nil || isFound {
+				return
+			}
+		}
+		return
+	}
+
+	// Cancel other probes after the first reference is found
+	ctx, cancel := context.WithCancel(db.Statement.Context)
+	defer cancel()
+
+	var (
+		wg        sync.WaitGroup
+		mutex     sync.Mutex
+		semaphore = make(chan struct{}, concurrency)
+	)
+
+loopTable:
+	for _, tableName := range listTable {
+		select {
+		case <-ctx.Done():
+			break loopTable
+		case semaphore <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(tableName, fieldName string) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			isFoundTable, errProbe := probeReference(db.WithContext(ctx), schemas, cfg, tableName, fieldName, ids)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if isFoundTable {
+				isFound = true
+				cancel()
+			} else if errProbe != nil && err == nil && ctx.Err() == nil {
+				err = errProbe
+			}
+		}(tableName, rmap[tableName])
+	}
+	wg.Wait()
+
+	// Error of cancelled probes is not needed
+	if isFound {
+		err = nil
+	}
+
+	return
+}
+
+// isInTransaction - statement of db runs on a transaction, ex: *sql.Tx
+func isInTransaction(db *gorm.DB) bool {
+	if db.Statement == nil {
+		return false
+	}
+
+	_, ok := db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok
+}
+
+// generateViolationCountQuery - count live rows of table which are referencing ids
+func generateViolationCountQuery(db *gorm.DB, schemas modelSchemas, cfg protectionConfig, tableName, fieldName string, ids []uuid.UUID) (query string, vars []interface{}, err error) {
+	from, where, vars, err := generateReferenceCondition(db, schemas, cfg, tableName, fieldName, ids)
+	if err != nil {
+		return
+	}
+
+	query = fmt.Sprintf(`SELECT COUNT(1) AS %s FROM %s WHERE %s`, db.Statement.Quote("total"), from, where)
+	return
+}
+
+// generateViolationSampleQuery - first ids of live rows of table which are referencing ids.
+func generateViolationSampleQuery(db *gorm.DB, schemas modelSchemas, cfg protectionConfig, tableName, fieldName string, ids []uuid.UUID) (query string, vars []interface{}, err error) {
+	from, where, vars, err := generateReferenceCondition(db, schemas, cfg, tableName, fieldName, ids)
+	if err != nil {
+		return
+	}
+
+	if !schemas.hasColumn(tableName, "id") {
+		err = fmt.Errorf("column id of table %s is not found", tableName)
+		return
+	}
+
+	id := db.Statement.Quote(clause.Column{Table: tableName, Name: "id"})
+	query = fmt.Sprintf(`SELECT %s AS %s FROM %s WHERE %s ORDER BY %s LIMIT ?`, id, db.Statement.Quote("id"), from, where, id)
+	vars = append(vars, cfg.violationSampleSize)
+	return
+}
+
+// Violation - dependent table which is still referencing the data, so the data cannot be deleted
+type Violation struct {
+	UsedByTable  string      `json:"used_by_table"`
+	UsedByColumn string      `json:"used_by_column"`
+	Total        int64       `json:"total"`                   // total live rows referencing the data
+	ReferenceIDs []uuid.UUID `json:"reference_ids,omitempty"` // first referencing ids, only if sample size is set
+	// ReferencedTable - table of the deleted chain which is referenced, only set by transitive check
+	ReferencedTable string `json:"referenced_table,omitempty"`
+}
+
+// collectViolations - count references of every table of router map, tables without reference are skipped.
+// Only called after delete is blocked, so counting all rows does not slow down allowed delete.
+func collectViolations(db *gorm.DB, schemas modelSchemas, rmap routerMap, ids []uuid.UUID, cfg protectionConfig) (violations []Violation, err error) {
+	listTable := make([]string, 0, len(rmap))
+	for tableName := range rmap {
+		listTable = append(listTable, tableName)
+	}
+	sort.Strings(listTable)
+
+	for _, tableName := range listTable {
+		fieldName := rmap[tableName]
+
+		query, vars, errQuery := generateViolationCountQuery(db, schemas, cfg, tableName, fieldName, ids)
+		if errQuery != nil {
+			err = &ProtectionQueryError{Table: tableName, Column: fieldName, Err: errQuery}
+			return
+		}
+
+		violation := Violation{
+			UsedByTable:  tableName,
+			UsedByColumn: fieldName,
+		}
+		if errCount := db.Raw(query, vars...).Scan(&violation.Total).Error; errCount != nil {
+			err = &ProtectionQueryError{Table: tableName, Column: fieldName, Err: errCount}
+			return
+		}
+
+		if violation.Total == 0 {
+			continue
+		}
+
+		if cfg.violationSampleSize > 0 {
+			query, vars, errQuery = generateViolationSampleQuery(db, schemas, cfg, tableName, fieldName, ids)
+			if errQuery != nil {
+				err = &ProtectionQueryError{Table: tableName, Column: fieldName, Err: errQuery}
+				return
+			}
+
+			result := []struct {
+				ID uuid.UUID
+			}{}
+			if errSample := db.Raw(query, vars...).Scan(&result).Error; errSample != nil {
+				err = &ProtectionQueryError{Table: tableName, Column: fieldName, Err: errSample}
+				return
+			}
+
+			for _, row := range result {
+				violation.ReferenceIDs = append(violation.ReferenceIDs, row.ID)
+			}
+		}
+
+		violations = append(violations, violation)
+	}
+
+	return
+}
+
+// validateProtectionQuery - data is allowed to be deleted if no dependent table is referencing ids.
+// If protection query is failed, the result follows failure policy.
+// Violations are only collected if data is not allowed to be deleted.
+func validateProtectionQuery(db *gorm.DB, schemas modelSchemas, rmap routerMap, ids []uuid.UUID, cfg protectionConfig) (isAllowed bool, violations []Violation, err error) {
+	isFound, err := findReference(db, schemas, rmap, ids, cfg)
+	if err != nil {
+		log.Println("ERROR validateProtectionQuery:", err.Error())
+
+		if cfg.failurePolicy == FailOpen {
+			log.Println("WARNING validateProtectionQuery: fail open, data is allowed to be deleted without protection")
+			isAllowed, err = true, nil
+		}
+		return
+	}
+
+	isAllowed = !isFound
+	if isAllowed {
+		return
+	}
+
+	// Delete is already blocked, failed report must not change the decision
+	violations, errViolation := collectViolations(db, schemas, rmap, ids, cfg)
+	if errViolation != nil {
+		log.Println("ERROR validateProtectionQuery:", errViolation.Error())
+	}
+
+	return
+}
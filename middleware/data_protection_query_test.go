@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/google/uuid"
+	"github.com/terra-discover/bbcrs-helper-lib/pkg/lib"
+	standardModel "github.com/terra-discover/bbcrs-migration-lib/model"
+)
+
+func Test_findReference(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	sqlDB, _ := db.DB()
+	// in memory database only live on single connection
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	cityID := uuid.New()
+	city := standardModel.City{}
+	city.ID = &cityID
+	city.CityCode = lib.Strptr(lib.RandomChars(6))
+	city.CityName = lib.Strptr(lib.RandomChars(6))
+	err := db.Create(&city).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	// Same total of references on both tables must not be collapsed
+	cityTranslation := standardModel.CityTranslation{
+		CityID: city.ID,
+		CityTranslationAPI: standardModel.CityTranslationAPI{
+			LanguageCode: lib.Strptr("id"),
+			CityName:     lib.Strptr(lib.RandomChars(10)),
+		},
+	}
+	err = db.Create(&cityTranslation).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	airport := standardModel.Airport{
+		AirportAPI: standardModel.AirportAPI{
+			AirportCode: lib.Strptr(lib.RandomChars(6)),
+			AirportName: lib.Strptr(lib.RandomChars(6)),
+			CityID:      city.ID,
+		},
+	}
+	err = db.Create(&airport).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	schemas, _, err := parseModelSchemas(db, testSupport__GetModelMigrations())
+	utils.AssertEqual(t, nil, err, "parse model schemas")
+
+	rmap := routerMap{
+		"city_translation": "city_id",
+		"airport":          "city_id",
+	}
+
+	tests := []struct {
+		name        string
+		rmap        routerMap
+		ids         []uuid.UUID
+		concurrency int
+		wantIsFound bool
+		wantErr     bool
+	}{
+		{
+			name:        "sequential, referenced, found",
+			rmap:        rmap,
+			ids:         []uuid.UUID{cityID},
+			concurrency: 1,
+			wantIsFound: true,
+		},
+		{
+			name:        "parallel, referenced, found",
+			rmap:        rmap,
+			ids:         []uuid.UUID{cityID},
+			concurrency: 2,
+			wantIsFound: true,
+		},
+		{
+			name:        "sequential, not referenced, not found",
+			rmap:        rmap,
+			ids:         []uuid.UUID{uuid.New()},
+			concurrency: 1,
+			wantIsFound: false,
+		},
+		{
+			name:        "parallel, not referenced, not found",
+			rmap:        rmap,
+			ids:         []uuid.UUID{uuid.New()},
+			concurrency: 2,
+			wantIsFound: false,
+		},
+		{
+			name:        "empty router map, not found",
+			rmap:        routerMap{},
+			ids:         []uuid.UUID{cityID},
+			concurrency: 1,
+			wantIsFound: false,
+		},
+		{
+			name: "table not listed on model migrations, error",
+			rmap: routerMap{
+				"not_exist_table": "city_id",
+			},
+			ids:         []uuid.UUID{cityID},
+			concurrency: 2,
+			wantIsFound: false,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotIsFound, err := findReference(db, schemas, tt.rmap, tt.ids, protectionConfig{probeConcurrency: tt.concurrency})
+			utils.AssertEqual(t, tt.wantErr, err != nil, "validate err")
+			utils.AssertEqual(t, tt.wantIsFound, gotIsFound, "validate is found")
+		})
+	}
+}
+
+func Test_collectViolations(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	cityID := uuid.New()
+	city := standardModel.City{}
+	city.ID = &cityID
+	city.CityCode = lib.Strptr(lib.RandomChars(6))
+	city.CityName = lib.Strptr(lib.RandomChars(6))
+	err := db.Create(&city).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	listAirportID := []uuid.UUID{}
+	for i := 0; i < 3; i++ {
+		airport := standardModel.Airport{
+			AirportAPI: standardModel.AirportAPI{
+				AirportCode: lib.Strptr(lib.RandomChars(6)),
+				AirportName: lib.Strptr(lib.RandomChars(6)),
+				CityID:      city.ID,
+			},
+		}
+		err = db.Create(&airport).Error
+		utils.AssertEqual(t, nil, err, "mock data")
+		listAirportID = append(listAirportID, *airport.ID)
+	}
+
+	// Deleted row is not a violation
+	err = db.Delete(&standardModel.Airport{}, "id = ?", listAirportID[2]).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	schemas, _, err := parseModelSchemas(db, testSupport__GetModelMigrations())
+	utils.AssertEqual(t, nil, err, "parse model schemas")
+
+	rmap := routerMap{
+		"city_translation": "city_id",
+		"airport":          "city_id",
+	}
+
+	// Case 1: only total, table without reference is skipped
+	violations, err := collectViolations(db, schemas, rmap, []uuid.UUID{cityID}, protectionConfig{violationSampleSize: 0})
+	utils.AssertEqual(t, nil, err, "validate err")
+	utils.AssertEqual(t, 1, len(violations), "validate violations")
+	utils.AssertEqual(t, "airport", violations[0].UsedByTable, "validate table")
+	utils.AssertEqual(t, "city_id", violations[0].UsedByColumn, "validate column")
+	utils.AssertEqual(t, int64(2), violations[0].Total, "validate total")
+	utils.AssertEqual(t, 0, len(violations[0].ReferenceIDs), "validate reference ids")
+
+	// Case 2: sample size is smaller than total
+	violations, err = collectViolations(db, schemas, rmap, []uuid.UUID{cityID}, protectionConfig{violationSampleSize: 1})
+	utils.AssertEqual(t, nil, err, "validate err")
+	utils.AssertEqual(t, int64(2), violations[0].Total, "validate total")
+	utils.AssertEqual(t, 1, len(violations[0].ReferenceIDs), "validate reference ids")
+
+	// Case 3: sample size is bigger than total
+	violations, err = collectViolations(db, schemas, rmap, []uuid.UUID{cityID}, protectionConfig{violationSampleSize: 5})
+	utils.AssertEqual(t, nil, err, "validate err")
+	utils.AssertEqual(t, 2, len(violations[0].ReferenceIDs), "validate reference ids")
+	for _, id := range violations[0].ReferenceIDs {
+		utils.AssertEqual(t, true, id == listAirportID[0] || id == listAirportID[1], "validate reference id")
+	}
+
+	// Case 4: not referenced
+	violations, err = collectViolations(db, schemas, rmap, []uuid.UUID{uuid.New()}, protectionConfig{violationSampleSize: 5})
+	utils.AssertEqual(t, nil, err, "validate err")
+	utils.AssertEqual(t, 0, len(violations), "validate violations")
+
+	// Case 5: table not listed on model migrations
+	_, err = collectViolations(db, schemas, routerMap{"not_exist_table": "city_id"}, []uuid.UUID{cityID}, protectionConfig{violationSampleSize: 0})
+	var errQuery *ProtectionQueryError
+	utils.AssertEqual(t, true, errors.As(err, &errQuery), "validate err")
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strings"
@@ -99,7 +100,8 @@ func Test_getListRelationSchema(t *testing.T) {
 
 func Test_generateDeleteRouteMaps(t *testing.T) {
 	type args struct {
-		db *gorm.DB
+		db           *gorm.DB
+		routerSource RouterSource
 	}
 	tests := []struct {
 		name                string
@@ -110,7 +112,8 @@ func Test_generateDeleteRouteMaps(t *testing.T) {
 		{
 			name: "",
 			args: args{
-				db: testSupport__DBConnectAndSeedTest(),
+				db:           testSupport__DBConnectAndSeedTest(),
+				routerSource: RouterSource{},
 			},
 			wantDeleteRouteMaps: routerMaps{},
 			wantErr:             false,
@@ -118,7 +121,7 @@ func Test_generateDeleteRouteMaps(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotDeleteRouteMaps, err := generateDeleteRouteMaps(tt.args.db)
+			gotDeleteRouteMaps, err := generateDeleteRouteMaps(tt.args.db, tt.args.routerSource)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("generateDeleteRouteMaps() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -137,9 +140,15 @@ func TestDataProtection(t *testing.T) {
 		sqlDB.Close()
 	})
 
+	registry := newRouterRegistry()
+	cache := newRouteMapsCache()
+	schemas, fieldTypes, err := parseModelSchemas(db, testSupport__GetModelMigrations())
+	utils.AssertEqual(t, nil, err, "parse model schemas")
+	cache.setSchemas(schemas, fieldTypes)
+
 	app := fiber.New()
 	app.Use(func(c *fiber.Ctx) error {
-		return runDataProtection(c, db)
+		return runDataProtection(c, db, cache.loader(db, registry), protectionConfig{})
 	})
 
 	// Case 1: Only declare Source
@@ -159,14 +168,16 @@ func TestDataProtection(t *testing.T) {
 	utils.AssertEqual(t, nil, mod.Error)
 
 	// Set dummy config
-	deleteRouterSource = RouterSource{
+	registry.reset()
+	cache.invalidate()
+	registry.merge(RouterSource{
 		UseMasterPattern("agent-corporates"): SourceRelation{
 			Source: "corporate",
 		},
-	}
+	})
 
 	// GenerateDeleteRouteMaps first
-	generateDeleteRouteMaps(db)
+	generateDeleteRouteMaps(db, registry.get())
 
 	id := uuid.New()
 	agentCorporate := standardModel.AgentCorporate{}
@@ -210,14 +221,16 @@ func TestDataProtection(t *testing.T) {
 	utils.AssertEqual(t, nil, mod.Error)
 
 	// Set dummy config
-	deleteRouterSource = RouterSource{
+	registry.reset()
+	cache.invalidate()
+	registry.merge(RouterSource{
 		UseMasterPattern("cities"): SourceRelation{
 			Source: "city",
 		},
-	}
+	})
 
 	// GenerateDeleteRouteMaps first
-	generateDeleteRouteMaps(db)
+	generateDeleteRouteMaps(db, registry.get())
 
 	id = uuid.New()
 	city := standardModel.City{}
@@ -260,15 +273,17 @@ func TestDataProtection(t *testing.T) {
 	// Case 3: Using IgnoreRelation
 	// Result: Validate IgnoreRelation (Allowed)
 	// Set dummy config with ignore relation
-	deleteRouterSource = RouterSource{
+	registry.reset()
+	cache.invalidate()
+	registry.merge(RouterSource{
 		UseMasterPattern("cities"): SourceRelation{
 			Source:         "city",
 			IgnoreRelation: []string{"airport", "city_translation"},
 		},
-	}
+	})
 
 	// GenerateDeleteRouteMaps first
-	generateDeleteRouteMaps(db)
+	generateDeleteRouteMaps(db, registry.get())
 
 	res, body, err = lib.DeleteTest(app, "/api/v1/master/cities/"+id.String(), nil)
 	utils.AssertEqual(t, nil, err, "Must success")
@@ -278,17 +293,19 @@ func TestDataProtection(t *testing.T) {
 	// Case 4: Using RequiredRelation
 	// Result: Validate RequiredRelation (Not Allowed)
 	// Set dummy config
-	deleteRouterSource = RouterSource{
+	registry.reset()
+	cache.invalidate()
+	registry.merge(RouterSource{
 		UseMasterPattern("cities"): SourceRelation{
 			Source: "city",
 			RequiredRelation: []string{
 				"airport",
 			},
 		},
-	}
+	})
 
 	// GenerateDeleteRouteMaps first
-	generateDeleteRouteMaps(db)
+	generateDeleteRouteMaps(db, registry.get())
 
 	id = uuid.New()
 	city = standardModel.City{}
@@ -331,7 +348,9 @@ func TestDataProtection(t *testing.T) {
 	// Case 5: Using RequiredRelation and IgnoreRelation at the same time.
 	// Result: Will only validate RequiredRelation (Not Allowed)
 	// Set dummy config with ignore relation
-	deleteRouterSource = RouterSource{
+	registry.reset()
+	cache.invalidate()
+	registry.merge(RouterSource{
 		UseMasterPattern("cities"): SourceRelation{
 			Source: "city",
 			RequiredRelation: []string{
@@ -341,10 +360,10 @@ func TestDataProtection(t *testing.T) {
 				"airport",
 			},
 		},
-	}
+	})
 
 	// GenerateDeleteRouteMaps first
-	generateDeleteRouteMaps(db)
+	generateDeleteRouteMaps(db, registry.get())
 
 	res, body, err = lib.DeleteTest(app, "/api/v1/master/cities/"+id.String(), nil)
 	utils.AssertEqual(t, nil, err, "Must success")
@@ -368,15 +387,17 @@ func TestDataProtection(t *testing.T) {
 	utils.AssertEqual(t, nil, mod.Error)
 
 	// Set dummy config
-	deleteRouterSource = RouterSource{
+	registry.reset()
+	cache.invalidate()
+	registry.merge(RouterSource{
 		UseMasterPattern("countries"): SourceRelation{
 			Source:         "country",
 			IgnoreRelation: []string{"country_translation"},
 		},
-	}
+	})
 
 	// GenerateDeleteRouteMaps first
-	generateDeleteRouteMaps(db)
+	generateDeleteRouteMaps(db, registry.get())
 
 	id = uuid.New()
 	country := standardModel.Country{}
@@ -443,16 +464,12 @@ func TestRouterSource_toRouterMaps(t *testing.T) {
 	}{
 		{
 			name: "generated 1 router maps, using required relation, not error",
-			rs: func() *RouterSource {
-				deleteRouterSource = RouterSource{
-					setDummyPattern("/my-endpoint", "cities"): SourceRelation{
-						Source:           "city",
-						RequiredRelation: []string{"state_province"},
-					},
-				}
-				newRs := deleteRouterSource
-				return &newRs
-			}(),
+			rs: &RouterSource{
+				setDummyPattern("/my-endpoint", "cities"): SourceRelation{
+					Source:           "city",
+					RequiredRelation: []string{"state_province"},
+				},
+			},
 			args: args{
 				listRelationSchema: []model.RelationSchema{
 					{
@@ -472,16 +489,12 @@ func TestRouterSource_toRouterMaps(t *testing.T) {
 		},
 		{
 			name: "generated 1 router maps, using ignore relation, not error",
-			rs: func() *RouterSource {
-				deleteRouterSource = RouterSource{
-					setDummyPattern("/my-endpoint", "cities"): SourceRelation{
-						Source:         "city",
-						IgnoreRelation: []string{"city_translation"},
-					},
-				}
-				newRs := deleteRouterSource
-				return &newRs
-			}(),
+			rs: &RouterSource{
+				setDummyPattern("/my-endpoint", "cities"): SourceRelation{
+					Source:         "city",
+					IgnoreRelation: []string{"city_translation"},
+				},
+			},
 			args: args{
 				listRelationSchema: []model.RelationSchema{
 					{
@@ -507,16 +520,12 @@ func TestRouterSource_toRouterMaps(t *testing.T) {
 		},
 		{
 			name: "relation schema.table source is nil, error",
-			rs: func() *RouterSource {
-				deleteRouterSource = RouterSource{
-					setDummyPattern("/my-endpoint", "cities"): SourceRelation{
-						Source:         "city",
-						IgnoreRelation: []string{"city_translation"},
-					},
-				}
-				newRs := deleteRouterSource
-				return &newRs
-			}(),
+			rs: &RouterSource{
+				setDummyPattern("/my-endpoint", "cities"): SourceRelation{
+					Source:         "city",
+					IgnoreRelation: []string{"city_translation"},
+				},
+			},
 			args: args{
 				listRelationSchema: []model.RelationSchema{
 					{
@@ -532,16 +541,12 @@ func TestRouterSource_toRouterMaps(t *testing.T) {
 		},
 		{
 			name: "relation schema.used by table is nil, error",
-			rs: func() *RouterSource {
-				deleteRouterSource = RouterSource{
-					setDummyPattern("/my-endpoint", "cities"): SourceRelation{
-						Source:         "city",
-						IgnoreRelation: []string{"city_translation"},
-					},
-				}
-				newRs := deleteRouterSource
-				return &newRs
-			}(),
+			rs: &RouterSource{
+				setDummyPattern("/my-endpoint", "cities"): SourceRelation{
+					Source:         "city",
+					IgnoreRelation: []string{"city_translation"},
+				},
+			},
 			args: args{
 				listRelationSchema: []model.RelationSchema{
 					{
@@ -557,16 +562,12 @@ func TestRouterSource_toRouterMaps(t *testing.T) {
 		},
 		{
 			name: "relation schema.used by table is nil, error",
-			rs: func() *RouterSource {
-				deleteRouterSource = RouterSource{
-					setDummyPattern("/my-endpoint", "cities"): SourceRelation{
-						Source:         "city",
-						IgnoreRelation: []string{"city_translation"},
-					},
-				}
-				newRs := deleteRouterSource
-				return &newRs
-			}(),
+			rs: &RouterSource{
+				setDummyPattern("/my-endpoint", "cities"): SourceRelation{
+					Source:         "city",
+					IgnoreRelation: []string{"city_translation"},
+				},
+			},
 			args: args{
 				listRelationSchema: []model.RelationSchema{
 					{
@@ -596,59 +597,116 @@ func TestRouterSource_toRouterMaps(t *testing.T) {
 }
 
 func Test_generateDataProtectionQuery(t *testing.T) {
-	uuid1 := *lib.GenUUID()
-	uuid2 := *lib.GenUUID()
+	// Identifiers are quoted by sqlite dialector
+	db := testSupport__DBConnectTest()
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	schemas := modelSchemas{
+		"city": {
+			"id":         true,
+			"country_id": true,
+			"deleted_at": true,
+		},
+		"city_log": {
+			"id":       true,
+			"city_id":  true,
+			"agent_id": true,
+		},
+	}
+
+	tenancy := protectionConfig{
+		tenantColumn: "agent_id",
+		tenantID:     uuid.New().String(),
+	}
 
 	type args struct {
-		tables routerMap
-		ids    []uuid.UUID
+		cfg       protectionConfig
+		tableName string
+		fieldName string
 	}
 	tests := []struct {
-		name string
-		args args
-		want string
+		name     string
+		args     args
+		want     string
+		wantVars int
+		wantErr  bool
 	}{
 		{
-			name: "2 queries generated, not error",
+			name: "soft delete table, query generated, not error",
 			args: args{
-				tables: routerMap{
-					"city":           "country_id",
-					"state_province": "country_id",
-				},
-				ids: []uuid.UUID{
-					uuid1,
-					uuid2,
-				},
+				tableName: "city",
+				fieldName: "country_id",
 			},
-			want: fmt.Sprintf(`
-				SELECT SUM("s"."total") "total" FROM (
-					SELECT COUNT(*) total FROM "city" 
-					WHERE "city"."country_id" IN(%[1]s) AND "city"."deleted_at" IS NULL
-					UNION
-					SELECT COUNT(*) total FROM "state_province" 
-					WHERE "state_province"."country_id" IN(%[1]s) AND "state_province"."deleted_at" IS NULL
-				) "s"
-			`,
-				lib.ConvertSliceUUIDToStr([]uuid.UUID{
-					uuid1,
-					uuid2,
-				}, ",", `'%s'`),
-			),
+			want: "SELECT 1 AS `found` FROM `city` " +
+				"WHERE `city`.`country_id` IN ? AND `city`.`deleted_at` IS NULL " +
+				"LIMIT 1",
+			wantVars: 1,
 		},
 		{
-			name: "no query generated, not error",
+			name: "table without deleted_at, query generated, not error",
 			args: args{
-				ids: []uuid.UUID{
-					uuid1,
-					uuid2,
-				},
+				tableName: "city_log",
+				fieldName: "city_id",
+			},
+			want: "SELECT 1 AS `found` FROM `city_log` " +
+				"WHERE `city_log`.`city_id` IN ? " +
+				"LIMIT 1",
+			wantVars: 1,
+		},
+		{
+			name: "tenancy mode, table with tenant column, query scoped by tenant, not error",
+			args: args{
+				cfg:       tenancy,
+				tableName: "city_log",
+				fieldName: "city_id",
+			},
+			want: "SELECT 1 AS `found` FROM `city_log` " +
+				"WHERE `city_log`.`city_id` IN ? AND `city_log`.`agent_id` = ? " +
+				"LIMIT 1",
+			wantVars: 2,
+		},
+		{
+			name: "tenancy mode, table without tenant column, query not scoped, not error",
+			args: args{
+				cfg:       tenancy,
+				tableName: "city",
+				fieldName: "country_id",
+			},
+			want: "SELECT 1 AS `found` FROM `city` " +
+				"WHERE `city`.`country_id` IN ? AND `city`.`deleted_at` IS NULL " +
+				"LIMIT 1",
+			wantVars: 1,
+		},
+		{
+			name: "table not listed on model migrations, error",
+			args: args{
+				tableName: `city" WHERE 1=1; --`,
+				fieldName: "country_id",
+			},
+			wantErr: true,
+		},
+		{
+			name: "column not listed on model migrations, error",
+			args: args{
+				tableName: "city",
+				fieldName: `country_id" IS NOT NULL OR "1`,
 			},
-			want: "",
+			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := generateDataProtectionQuery(tt.args.tables, tt.args.ids)
+			got, gotVars, err := generateDataProtectionQuery(db, schemas, tt.args.cfg, tt.args.tableName, tt.args.fieldName, []uuid.UUID{uuid.New()})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("generateDataProtectionQuery() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if len(gotVars) != tt.wantVars {
+				t.Errorf("generateDataProtectionQuery() vars = %v, want %v", len(gotVars), tt.wantVars)
+			}
 			gotNoSpace := strings.Join(strings.Fields(got), " ")
 			wantNoSpace := strings.Join(strings.Fields(tt.want), " ")
 			if !strings.EqualFold(gotNoSpace, wantNoSpace) {
@@ -657,3 +715,181 @@ func Test_generateDataProtectionQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestDeactivateProtection(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	listRelationSchema := []standardModel.RelationSchema{
+		{
+			ColumnSource: lib.Strptr("id"),
+			TableSource:  lib.Strptr("city"),
+			UsedByColumn: lib.Strptr("city_id"),
+			UsedByTable:  lib.Strptr("airport"),
+		},
+		{
+			ColumnSource: lib.Strptr("id"),
+			TableSource:  lib.Strptr("city"),
+			UsedByColumn: lib.Strptr("city_id"),
+			UsedByTable:  lib.Strptr("city_translation"),
+		},
+	}
+	for i := range listRelationSchema {
+		mod := db.Create(&listRelationSchema[i])
+		utils.AssertEqual(t, nil, mod.Error)
+	}
+
+	// Only translation blocks deactivation
+	m := NewMiddleware(Environment{}, db)
+	m.ReplaceRouterSource(RouterSource{
+		UseMasterPattern("cities"): SourceRelation{
+			Source: "city",
+			Deactivate: &DeactivateRule{
+				Field:            "is_active",
+				Value:            false,
+				BatchActions:     []string{"deactivate"},
+				RequiredRelation: []string{"city_translation"},
+			},
+		},
+	}).SetModelMigrations(testSupport__GetModelMigrations())
+	utils.AssertEqual(t, nil, m.Error, "validate err")
+
+	app := fiber.New()
+	app.Use(m.Protect)
+	app.Delete("/api/v1/master/cities/:id", func(c *fiber.Ctx) error {
+		return c.Status(200).JSON(fiber.Map{"status": 200})
+	})
+	app.Put("/api/v1/master/cities/:id", func(c *fiber.Ctx) error {
+		return c.Status(200).JSON(fiber.Map{"status": 200})
+	})
+	app.Post("/api/v1/master/batch-actions/:action/:module", func(c *fiber.Ctx) error {
+		return c.Status(200).JSON(fiber.Map{"status": 200})
+	})
+
+	createCity := func() uuid.UUID {
+		cityID := uuid.New()
+		city := standardModel.City{}
+		city.ID = &cityID
+		city.CityCode = lib.Strptr(lib.RandomChars(6))
+		city.CityName = lib.Strptr(lib.RandomChars(6))
+		err := db.Create(&city).Error
+		utils.AssertEqual(t, nil, err, "mock data")
+		return cityID
+	}
+
+	airportCityID := createCity()
+	airport := standardModel.Airport{
+		AirportAPI: standardModel.AirportAPI{
+			AirportCode: lib.Strptr(lib.RandomChars(6)),
+			AirportName: lib.Strptr(lib.RandomChars(6)),
+			CityID:      &airportCityID,
+		},
+	}
+	err := db.Create(&airport).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	translationCityID := createCity()
+	cityTranslation := standardModel.CityTranslation{
+		CityID: &translationCityID,
+		CityTranslationAPI: standardModel.CityTranslationAPI{
+			LanguageCode: lib.Strptr("id"),
+			CityName:     lib.Strptr(lib.RandomChars(10)),
+		},
+	}
+	err = db.Create(&cityTranslation).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		wantStatus  int
+		wantMessage string
+	}{
+		{
+			name:        "deactivate by body, referenced by deactivate relation, rejected",
+			method:      fiber.MethodPut,
+			path:        "/api/v1/master/cities/" + translationCityID.String(),
+			body:        `{"is_active": false}`,
+			wantStatus:  405,
+			wantMessage: "deactivate",
+		},
+		{
+			name:       "deactivate by body, referenced by other relation, allowed",
+			method:     fiber.MethodPut,
+			path:       "/api/v1/master/cities/" + airportCityID.String(),
+			body:       `{"is_active": false}`,
+			wantStatus: 200,
+		},
+		{
+			name:       "activate by body, referenced, allowed",
+			method:     fiber.MethodPut,
+			path:       "/api/v1/master/cities/" + translationCityID.String(),
+			body:       `{"is_active": true}`,
+			wantStatus: 200,
+		},
+		{
+			name:        "deactivate batch action, referenced by deactivate relation, rejected",
+			method:      fiber.MethodPost,
+			path:        "/api/v1/master/batch-actions/deactivate/cities",
+			body:        fmt.Sprintf(`["%s", "%s"]`, airportCityID, translationCityID),
+			wantStatus:  405,
+			wantMessage: "deactivate",
+		},
+		{
+			name:       "deactivate batch action, referenced by other relation, allowed",
+			method:     fiber.MethodPost,
+			path:       "/api/v1/master/batch-actions/deactivate/cities",
+			body:       fmt.Sprintf(`["%s"]`, airportCityID),
+			wantStatus: 200,
+		},
+		{
+			name:       "other batch action, not protected",
+			method:     fiber.MethodPost,
+			path:       "/api/v1/master/batch-actions/activate/cities",
+			body:       fmt.Sprintf(`["%s"]`, translationCityID),
+			wantStatus: 200,
+		},
+		{
+			name:       "deactivate batch action, invalid body, bad request",
+			method:     fiber.MethodPost,
+			path:       "/api/v1/master/batch-actions/deactivate/cities",
+			body:       `{"ids": "invalid"}`,
+			wantStatus: 400,
+		},
+		{
+			name:       "other batch action, body is not parsed, not protected",
+			method:     fiber.MethodPost,
+			path:       "/api/v1/master/batch-actions/activate/cities",
+			body:       `{"ids": "invalid"}`,
+			wantStatus: 200,
+		},
+		{
+			name:        "delete, referenced by delete relation, rejected",
+			method:      fiber.MethodDelete,
+			path:        "/api/v1/master/cities/" + airportCityID.String(),
+			wantStatus:  405,
+			wantMessage: "delete",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{fiber.HeaderContentType: fiber.MIMEApplicationJSON}
+			res, err := app.Test(lib.HTTPRequest(tt.method, tt.path, headers, tt.body))
+			utils.AssertEqual(t, nil, err, "Must success")
+			utils.AssertEqual(t, tt.wantStatus, res.StatusCode, "validate status")
+
+			if tt.wantMessage != "" {
+				body := ViolationResponse{}
+				err = json.NewDecoder(res.Body).Decode(&body)
+				utils.AssertEqual(t, nil, err, "decode body")
+				utils.AssertEqual(t, true, strings.Contains(body.Message, tt.wantMessage), "validate message")
+			}
+		})
+	}
+}
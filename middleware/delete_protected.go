@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// skipPluginKey - delete which is already protected by DeleteProtected is not probed again by Plugin
+const skipPluginKey = "route_protection:skip_plugin"
+
+// DeleteProtected - lock source rows and referencing tables, probe dependent tables, then delete in the same transaction,
+// so no reference can be inserted between the check and the delete, even without foreign key constraint.
+// tx can be a transaction or a plain db, model is the source model, ex: &model.City{}.
+// Source rows are locked by SELECT ... FOR UPDATE. Referencing tables are locked until the transaction is finished:
+//
+//	postgres: LOCK TABLE ... IN SHARE ROW EXCLUSIVE MODE, writes of referencing tables wait for the transaction
+//	sqlite: writes are serialized by the database lock, a reference cannot be committed between the probe and the delete
+//
+// Other databases return ErrDeleteLockUnsupported, use CanDelete with foreign key constraints instead.
+// Nothing is deleted if the decision is not allowed, *ProtectionViolationError is returned with the decision.
+// gorm.ErrRecordNotFound is returned if no live row is found.
+// Rows of ignored relations are cascaded or nullified by SourceRelation.IgnoreActions after the delete.
+func (m *Middleware) DeleteProtected(ctx context.Context, tx *gorm.DB, model interface{}, ids []uuid.UUID) (decision Decision, err error) {
+	if m.isRouterSourceEmpty() {
+		err = ErrRouterSourceEmpty
+		return
+	}
+
+	// Route maps are built by m.db before the transaction, so no statement of cache build runs while rows are locked,
+	// and a failed statement cannot abort the transaction
+	cfg := m.contextConfig(ctx)
+	routes, errLoad := m.cache.get(m.db, m.registry)
+	if errLoad != nil {
+		// Fail open deletes without protection, so ignored relations are not applied
+		if _, err = cfg.onLoadFailure(errLoad); err != nil {
+			return
+		}
+	}
+
+	err = tx.WithContext(ctx).Transaction(func(tx *gorm.DB) (err error) {
+		decision, err = deleteProtected(tx, routes, cfg, model, ids)
+		return
+	})
+	return
+}
+
+func deleteProtected(tx *gorm.DB, routes compiledRouteMaps, cfg protectionConfig, model interface{}, ids []uuid.UUID) (decision Decision, err error) {
+	stmt := &gorm.Statement{DB: tx}
+	if err = stmt.Parse(model); err != nil {
+		return
+	}
+
+	primaryField := stmt.Schema.PrioritizedPrimaryField
+	if primaryField == nil {
+		err = fmt.Errorf("primary key of table %s is not found", stmt.Table)
+		return
+	}
+	primaryColumn := clause.Column{Table: stmt.Table, Name: primaryField.DBName}
+
+	// Lock live source rows until the transaction is finished
+	lockedIds := []uuid.UUID{}
+	err = tx.Model(model).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where(clause.IN{Column: primaryColumn, Values: toValues(ids)}).
+		Pluck(primaryField.DBName, &lockedIds).Error
+	if err != nil {
+		return
+	}
+	if len(lockedIds) == 0 {
+		err = gorm.ErrRecordNotFound
+		return
+	}
+
+	decision.IsAllowed = true
+	if rmap, ok := routes.isProtectedTable(stmt.Table, cfg); ok {
+		if err = lockReferencingTables(tx, referencingTables(routes, stmt.Table, rmap, cfg)); err != nil {
+			return
+		}
+
+		decision, err = decideDelete(tx, routes, stmt.Table, rmap, lockedIds, cfg)
+		if err != nil {
+			return
+		}
+		if !decision.IsAllowed {
+			err = newViolationError(stmt.Table, lockedIds, decision)
+			return
+		}
+	}
+
+	err = tx.Set(skipPluginKey, true).
+		Where(clause.IN{Column: primaryColumn, Values: toValues(lockedIds)}).
+		Delete(model).Error
+	if err != nil {
+		return
+	}
+
+	err = applyIgnoreActions(tx, routes.ignoredTables[stmt.Table], lockedIds)
+	return
+}
+
+// referencingTables - sorted tables which are probed for delete of source,
+// including tables of ignored and cascaded relations in transitive mode
+func referencingTables(routes compiledRouteMaps, source string, rmap routerMap, cfg protectionConfig) (listTable []string) {
+	isExist := make(map[string]bool)
+	for tableName := range rmap {
+		isExist[tableName] = true
+	}
+
+	if cfg.isTransitive {
+		isWalked := map[string]bool{source: true}
+		queue := []string{source}
+		for len(queue) > 0 {
+			node := routes.dependencyGraph[queue[0]]
+			queue = queue[1:]
+
+			for _, edge := range node.block {
+				isExist[edge.table] = true
+			}
+			for _, edge := range node.walk {
+				isExist[edge.table] = true
+				if !isWalked[edge.table] {
+					isWalked[edge.table] = true
+					queue = append(queue, edge.table)
+				}
+			}
+		}
+	}
+
+	listTable = make([]string, 0, len(isExist))
+	for tableName := range isExist {
+		listTable = append(listTable, tableName)
+	}
+	sort.Strings(listTable)
+	return
+}
+
+// lockReferencingTables - block writes of referencing rows until the transaction is finished.
+// Tables are locked in sorted order, so concurrent deletes do not deadlock.
+func lockReferencingTables(tx *gorm.DB, listTable []string) (err error) {
+	switch name := tx.Dialector.Name(); name {
+	case "sqlite":
+		// Writes of sqlite are serialized by the database lock
+		return
+	case "postgres":
+		for _, tableName := range listTable {
+			query := fmt.Sprintf(`LOCK TABLE %s IN SHARE ROW EXCLUSIVE MODE`, tx.Statement.Quote(clause.Table{Name: tableName}))
+			if err = tx.Exec(query).Error; err != nil {
+				return
+			}
+		}
+		return
+	default:
+		err = fmt.Errorf("%w: %s", ErrDeleteLockUnsupported, name)
+		return
+	}
+}
+
+func toValues(ids []uuid.UUID) (values []interface{}) {
+	values = make([]interface{}, len(ids))
+	for i := range ids {
+		values[i] = ids[i]
+	}
+	return
+}
@@ -0,0 +1,285 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/google/uuid"
+	"github.com/terra-discover/bbcrs-helper-lib/pkg/lib"
+	standardModel "github.com/terra-discover/bbcrs-migration-lib/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestMiddleware_DeleteProtected(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	sqlDB, _ := db.DB()
+	// in memory database only live on single connection
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	relationSchema := standardModel.RelationSchema{
+		ColumnSource: lib.Strptr("id"),
+		TableSource:  lib.Strptr("city"),
+		UsedByColumn: lib.Strptr("city_id"),
+		UsedByTable:  lib.Strptr("airport"),
+	}
+	mod := db.Create(&relationSchema)
+	utils.AssertEqual(t, nil, mod.Error)
+
+	m := NewMiddleware(Environment{}, db)
+
+	// Case 1: router source empty, error
+	_, err := m.DeleteProtected(context.Background(), db, &standardModel.City{}, []uuid.UUID{uuid.New()})
+	utils.AssertEqual(t, true, errors.Is(err, ErrRouterSourceEmpty), "validate err")
+
+	m.ReplaceRouterSource(RouterSource{
+		UseMasterPattern("cities"): SourceRelation{
+			Source: "city",
+		},
+	}).SetModelMigrations(testSupport__GetModelMigrations())
+	utils.AssertEqual(t, nil, m.Error, "validate err")
+
+	// Delete which is protected by DeleteProtected is not probed again by plugin
+	err = db.Use(m.Plugin())
+	utils.AssertEqual(t, nil, err, "use plugin")
+
+	createCity := func() uuid.UUID {
+		cityID := uuid.New()
+		city := standardModel.City{}
+		city.ID = &cityID
+		city.CityCode = lib.Strptr(lib.RandomChars(6))
+		city.CityName = lib.Strptr(lib.RandomChars(6))
+		err := db.Create(&city).Error
+		utils.AssertEqual(t, nil, err, "mock data")
+		return cityID
+	}
+
+	usedCityID := createCity()
+	airport := standardModel.Airport{
+		AirportAPI: standardModel.AirportAPI{
+			AirportCode: lib.Strptr(lib.RandomChars(6)),
+			AirportName: lib.Strptr(lib.RandomChars(6)),
+			CityID:      &usedCityID,
+		},
+	}
+	err = db.Create(&airport).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	unusedCityID := createCity()
+
+	countCity := func(id uuid.UUID) (total int64) {
+		err := db.Model(&standardModel.City{}).Where("id = ?", id).Count(&total).Error
+		utils.AssertEqual(t, nil, err, "count city")
+		return
+	}
+
+	// Case 2: referenced, not deleted
+	var errViolation *ProtectionViolationError
+	decision, err := m.DeleteProtected(context.Background(), db, &standardModel.City{}, []uuid.UUID{usedCityID, unusedCityID})
+	utils.AssertEqual(t, true, errors.As(err, &errViolation), "validate err")
+	utils.AssertEqual(t, "city", errViolation.Table, "validate violation table")
+	utils.AssertEqual(t, "airport", errViolation.Violations[0].UsedByTable, "validate violations")
+	utils.AssertEqual(t, true, decision.IsProtected, "validate is protected")
+	utils.AssertEqual(t, false, decision.IsAllowed, "validate is allowed")
+	utils.AssertEqual(t, int64(1), countCity(usedCityID), "validate city is not deleted")
+	utils.AssertEqual(t, int64(1), countCity(unusedCityID), "validate city is not deleted")
+
+	// Case 3: not referenced, deleted inside caller transaction
+	err = db.Transaction(func(tx *gorm.DB) error {
+		decision, err = m.DeleteProtected(context.Background(), tx, &standardModel.City{}, []uuid.UUID{unusedCityID})
+		return err
+	})
+	utils.AssertEqual(t, nil, err, "validate err")
+	utils.AssertEqual(t, true, decision.IsAllowed, "validate is allowed")
+	utils.AssertEqual(t, int64(0), countCity(unusedCityID), "validate city is deleted")
+
+	// Case 4: already deleted, not found
+	_, err = m.DeleteProtected(context.Background(), db, &standardModel.City{}, []uuid.UUID{unusedCityID})
+	utils.AssertEqual(t, true, errors.Is(err, gorm.ErrRecordNotFound), "validate err")
+}
+
+func TestMiddleware_DeleteProtected_probeConcurrency(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	sqlDB, _ := db.DB()
+	// in memory database only live on single connection
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	listRelationSchema := []standardModel.RelationSchema{
+		{
+			ColumnSource: lib.Strptr("id"),
+			TableSource:  lib.Strptr("city"),
+			UsedByColumn: lib.Strptr("city_id"),
+			UsedByTable:  lib.Strptr("airport"),
+		},
+		{
+			ColumnSource: lib.Strptr("id"),
+			TableSource:  lib.Strptr("city"),
+			UsedByColumn: lib.Strptr("city_id"),
+			UsedByTable:  lib.Strptr("city_translation"),
+		},
+	}
+	mod := db.Create(&listRelationSchema)
+	utils.AssertEqual(t, nil, mod.Error)
+
+	m := NewMiddleware(Environment{}, db)
+	m.ReplaceRouterSource(RouterSource{
+		UseMasterPattern("cities"): SourceRelation{
+			Source: "city",
+		},
+	}).SetModelMigrations(testSupport__GetModelMigrations()).SetProbeConcurrency(4)
+	utils.AssertEqual(t, nil, m.Error, "validate err")
+
+	// Record probed tables, in the order of probe
+	var (
+		mutex       sync.Mutex
+		probeTables []string
+	)
+	err := db.Callback().Row().Before("gorm:row").Register("test:probe", func(tx *gorm.DB) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if sql := tx.Statement.SQL.String(); strings.Contains(sql, "LIMIT 1") {
+			probeTables = append(probeTables, strings.Split(strings.Split(sql, "FROM `")[1], "`")[0])
+		}
+	})
+	utils.AssertEqual(t, nil, err, "register callback")
+
+	createCity := func() uuid.UUID {
+		cityID := uuid.New()
+		city := standardModel.City{}
+		city.ID = &cityID
+		city.CityCode = lib.Strptr(lib.RandomChars(6))
+		city.CityName = lib.Strptr(lib.RandomChars(6))
+		err := db.Create(&city).Error
+		utils.AssertEqual(t, nil, err, "mock data")
+		return cityID
+	}
+
+	// Reference is found on the first table, airport, while city_translation is still probed
+	usedCityID := createCity()
+	airport := standardModel.Airport{
+		AirportAPI: standardModel.AirportAPI{
+			AirportCode: lib.Strptr(lib.RandomChars(6)),
+			AirportName: lib.Strptr(lib.RandomChars(6)),
+			CityID:      &usedCityID,
+		},
+	}
+	err = db.Create(&airport).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	unusedCityID := createCity()
+
+	// Referenced and not referenced delete in the same caller transaction, the transaction is still usable
+	var decisionUsed, decisionUnused Decision
+	err = db.Transaction(func(tx *gorm.DB) (err error) {
+		var errViolation *ProtectionViolationError
+		decisionUsed, err = m.DeleteProtected(context.Background(), tx, &standardModel.City{}, []uuid.UUID{usedCityID})
+		if !errors.As(err, &errViolation) {
+			return
+		}
+
+		// Tables are probed one by one inside transaction, city_translation is not probed after the first reference
+		mutex.Lock()
+		utils.AssertEqual(t, []string{"airport"}, probeTables, "validate sequential probe")
+		mutex.Unlock()
+
+		decisionUnused, err = m.DeleteProtected(context.Background(), tx, &standardModel.City{}, []uuid.UUID{unusedCityID})
+		return
+	})
+	utils.AssertEqual(t, nil, err, "validate err")
+	utils.AssertEqual(t, false, decisionUsed.IsAllowed, "validate referenced is not allowed")
+	utils.AssertEqual(t, true, decisionUnused.IsAllowed, "validate not referenced is allowed")
+
+}
+
+// testDialector - dialector of sqlite with another name, to test dialect specific statements
+type testDialector struct {
+	gorm.Dialector
+	name string
+}
+
+func (d testDialector) Name() string {
+	return d.name
+}
+
+func Test_lockReferencingTables(t *testing.T) {
+	tests := []struct {
+		name      string
+		dialector string
+		wantSQL   string
+		wantErr   error
+	}{
+		{
+			name:      "sqlite, serialized without lock",
+			dialector: "sqlite",
+		},
+		{
+			name:      "postgres, lock table",
+			dialector: "postgres",
+			wantSQL:   "LOCK TABLE `city_translation` IN SHARE ROW EXCLUSIVE MODE",
+		},
+		{
+			name:      "other database, unsupported",
+			dialector: "sqlserver",
+			wantErr:   ErrDeleteLockUnsupported,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := gorm.Open(testDialector{Dialector: sqlite.Open("file::memory:"), name: tt.dialector}, &gorm.Config{
+				Logger: logger.Default.LogMode(logger.Silent),
+				DryRun: true,
+			})
+			utils.AssertEqual(t, nil, err, "open db")
+
+			var listSQL []string
+			err = db.Callback().Raw().After("gorm:raw").Register("test:sql", func(tx *gorm.DB) {
+				listSQL = append(listSQL, tx.Statement.SQL.String())
+			})
+			utils.AssertEqual(t, nil, err, "register callback")
+
+			err = lockReferencingTables(db, []string{"airport", "city_translation"})
+			utils.AssertEqual(t, true, errors.Is(err, tt.wantErr), "validate err")
+
+			if tt.wantSQL != "" {
+				utils.AssertEqual(t, 2, len(listSQL), "validate locked tables")
+				utils.AssertEqual(t, tt.wantSQL, listSQL[1], "validate sql")
+			} else {
+				utils.AssertEqual(t, 0, len(listSQL), "validate locked tables")
+			}
+		})
+	}
+}
+
+func Test_referencingTables(t *testing.T) {
+	routes := compiledRouteMaps{
+		dependencyGraph: dependencyGraph{
+			"country": {
+				isSource: true,
+				walk:     []relationEdge{{table: "city", column: "country_id"}},
+				block:    []relationEdge{{table: "hotel", column: "country_id"}},
+			},
+			"city": {
+				walk:  []relationEdge{{table: "city_translation", column: "city_id"}},
+				block: []relationEdge{{table: "airport", column: "city_id"}},
+			},
+		},
+	}
+	rmap := routerMap{"hotel": "country_id"}
+
+	utils.AssertEqual(t, []string{"hotel"}, referencingTables(routes, "country", rmap, protectionConfig{}), "validate direct tables")
+	utils.AssertEqual(t, []string{"airport", "city", "city_translation", "hotel"},
+		referencingTables(routes, "country", rmap, protectionConfig{isTransitive: true}), "validate transitive tables")
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/terra-discover/bbcrs-helper-lib/pkg/lib"
+	"gorm.io/gorm"
+)
+
+// dependencySuffix - suffix of dependency preview route, trimmed to get the protected delete route
+const dependencySuffix = "/dependencies"
+
+// DependencyResponse - response of dependency preview
+type DependencyResponse struct {
+	lib.Response
+	Dependencies []Violation `json:"dependencies"`
+}
+
+// Dependencies - preview dependent tables of a record before deletion, without deleting it.
+// Mount it on GET <resource>/:id/dependencies of a protected delete route, ex:
+//
+//	app.Get("/api/v1/master/countries/:id/dependencies", m.Dependencies)
+//
+// Failed protection query or route maps are responded by failure policy, FailOpen has nothing to allow so it is responded with 503.
+func (m *Middleware) Dependencies(c *fiber.Ctx) error {
+	if m.isRouterSourceEmpty() {
+		return lib.ErrorInternal(c, ErrRouterSourceEmpty.Error())
+	}
+
+	cfg := m.requestConfig(c)
+	dependencies, errResp, err := previewDependencies(c.Path(), m.db.WithContext(c.UserContext()), m.cache.loader(m.db, m.registry), cfg)
+	if err != nil {
+		return cfg.onFailure(c, err)
+	}
+
+	if !errResp.IsEmpty() {
+		return errResp.SendToContext(c)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(DependencyResponse{
+		Response: lib.Response{
+			Status:  fiber.StatusOK,
+			Message: "Success",
+		},
+		Dependencies: dependencies,
+	})
+}
+
+// previewDependencies - dependent tables of the record which is deleted by the protected delete route of path.
+// Route maps are generated by toRouterMaps, so RequiredRelation and IgnoreRelation are honoured.
+// In transitive mode, rows referencing the walked chain of ignored and cascaded relations are listed too.
+func previewDependencies(path string, db *gorm.DB, loadRoutes routesLoader, cfg protectionConfig) (dependencies []Violation, errResp lib.ErrorResponse, err error) {
+	route := strings.TrimSuffix(strings.TrimSuffix(path, "/"), dependencySuffix)
+
+	routes, errLoad := loadRoutes()
+	if errLoad != nil {
+		log.Println("ERROR failed preview dependencies:", errLoad.Error())
+		err = &ProtectionLoadError{Err: errLoad}
+		return
+	}
+
+	deleteRoute, id := matchingRoute(routes.deleteRoutes, route)
+	if nil == deleteRoute || lib.IsEmptyUUIDPtr(id) {
+		errResp = lib.SetErrorNotFound("route is not protected")
+		return
+	}
+
+	ids := []uuid.UUID{*id}
+	dependencies, err = collectViolations(db, routes.schemas, deleteRoute.tables, ids, cfg)
+	if err != nil {
+		log.Println("ERROR failed preview dependencies:", err.Error())
+		return
+	}
+
+	// Same transitive walk of delete, so preview lists every dependency which blocks the delete
+	if cfg.isTransitive {
+		transitiveDependencies, errTransitive := collectTransitiveViolations(db, routes.dependencyGraph, routes.schemas, deleteRoute.source, ids, cfg)
+		if errTransitive != nil {
+			log.Println("ERROR failed preview dependencies:", errTransitive.Error())
+			err = errTransitive
+			return
+		}
+
+		dependencies = append(dependencies, transitiveDependencies...)
+	}
+
+	// Respond empty list instead of null
+	if dependencies == nil {
+		dependencies = []Violation{}
+	}
+
+	return
+}
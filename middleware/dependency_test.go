@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/google/uuid"
+	"github.com/terra-discover/bbcrs-helper-lib/pkg/lib"
+	standardModel "github.com/terra-discover/bbcrs-migration-lib/model"
+)
+
+func TestMiddleware_Dependencies(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	m := NewMiddleware(Environment{}, db)
+
+	app := fiber.New()
+	app.Get("/api/v1/master/cities/:id/dependencies", m.Dependencies)
+	app.Get("/api/v1/master/countries/:id/dependencies", m.Dependencies)
+
+	// Case 1: router source empty, error
+	res, _, err := lib.GetTest(app, "/api/v1/master/cities/"+uuid.New().String()+"/dependencies", nil)
+	utils.AssertEqual(t, nil, err, "Must success")
+	utils.AssertEqual(t, 500, res.StatusCode, "Must be error")
+
+	listRelationSchema := []standardModel.RelationSchema{
+		{
+			ColumnSource: lib.Strptr("id"),
+			TableSource:  lib.Strptr("city"),
+			UsedByColumn: lib.Strptr("city_id"),
+			UsedByTable:  lib.Strptr("airport"),
+		},
+		{
+			ColumnSource: lib.Strptr("id"),
+			TableSource:  lib.Strptr("city"),
+			UsedByColumn: lib.Strptr("city_id"),
+			UsedByTable:  lib.Strptr("city_translation"),
+		},
+	}
+	for i := range listRelationSchema {
+		mod := db.Create(&listRelationSchema[i])
+		utils.AssertEqual(t, nil, mod.Error)
+	}
+
+	m.ReplaceRouterSource(RouterSource{
+		UseMasterPattern("cities"): SourceRelation{
+			Source:         "city",
+			IgnoreRelation: []string{"city_translation"},
+		},
+	}).SetModelMigrations(testSupport__GetModelMigrations())
+	utils.AssertEqual(t, nil, m.Error, "validate err")
+
+	cityID := uuid.New()
+	city := standardModel.City{}
+	city.ID = &cityID
+	city.CityCode = lib.Strptr(lib.RandomChars(6))
+	city.CityName = lib.Strptr(lib.RandomChars(6))
+	err = db.Create(&city).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	airport := standardModel.Airport{
+		AirportAPI: standardModel.AirportAPI{
+			AirportCode: lib.Strptr(lib.RandomChars(6)),
+			AirportName: lib.Strptr(lib.RandomChars(6)),
+			CityID:      city.ID,
+		},
+	}
+	err = db.Create(&airport).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	// Ignored relation is not a dependency
+	cityTranslation := standardModel.CityTranslation{
+		CityID: city.ID,
+		CityTranslationAPI: standardModel.CityTranslationAPI{
+			LanguageCode: lib.Strptr("id"),
+			CityName:     lib.Strptr(lib.RandomChars(10)),
+		},
+	}
+	err = db.Create(&cityTranslation).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	// Case 2: referenced record
+	res, body, err := lib.GetTest(app, "/api/v1/master/cities/"+cityID.String()+"/dependencies", nil)
+	utils.AssertEqual(t, nil, err, "Must success")
+	utils.AssertEqual(t, 200, res.StatusCode, "validate status")
+
+	dependencies, _ := body["dependencies"].([]interface{})
+	utils.AssertEqual(t, 1, len(dependencies), "validate dependencies")
+	dependency, _ := dependencies[0].(map[string]interface{})
+	utils.AssertEqual(t, "airport", dependency["used_by_table"], "validate table")
+	utils.AssertEqual(t, float64(1), dependency["total"], "validate total")
+
+	// Case 3: not referenced record
+	res, body, err = lib.GetTest(app, "/api/v1/master/cities/"+uuid.New().String()+"/dependencies", nil)
+	utils.AssertEqual(t, nil, err, "Must success")
+	utils.AssertEqual(t, 200, res.StatusCode, "validate status")
+	utils.AssertEqual(t, []interface{}{}, body["dependencies"], "validate dependencies")
+
+	// Case 4: route is not protected
+	res, _, err = lib.GetTest(app, "/api/v1/master/countries/"+uuid.New().String()+"/dependencies", nil)
+	utils.AssertEqual(t, nil, err, "Must success")
+	utils.AssertEqual(t, 404, res.StatusCode, "validate status")
+}
+
+func TestMiddleware_Dependencies_transitive(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	listRelationSchema := []standardModel.RelationSchema{
+		{
+			ColumnSource: lib.Strptr("id"),
+			TableSource:  lib.Strptr("corporate"),
+			UsedByColumn: lib.Strptr("parent_corporate_id"),
+			UsedByTable:  lib.Strptr("corporate"),
+		},
+		{
+			ColumnSource: lib.Strptr("id"),
+			TableSource:  lib.Strptr("corporate"),
+			UsedByColumn: lib.Strptr("corporate_id"),
+			UsedByTable:  lib.Strptr("agent_corporate"),
+		},
+	}
+	for i := range listRelationSchema {
+		mod := db.Create(&listRelationSchema[i])
+		utils.AssertEqual(t, nil, mod.Error)
+	}
+
+	m := NewMiddleware(Environment{}, db)
+	m.ReplaceRouterSource(RouterSource{
+		UseMasterPattern("corporates"): SourceRelation{
+			Source:         "corporate",
+			IgnoreRelation: []string{"corporate"},
+		},
+	}).SetModelMigrations(testSupport__GetModelMigrations())
+	utils.AssertEqual(t, nil, m.Error, "validate err")
+
+	app := fiber.New()
+	app.Get("/api/v1/master/corporates/:id/dependencies", m.Dependencies)
+
+	// Mock data, parent corporate -> child corporate -> agent corporate
+	parentID, childID := uuid.New(), uuid.New()
+	parent := standardModel.Corporate{CorporateName: lib.Strptr("Parent")}
+	parent.ID = &parentID
+	child := standardModel.Corporate{CorporateName: lib.Strptr("Child"), ParentCorporateID: &parentID}
+	child.ID = &childID
+	err := db.Create(&[]standardModel.Corporate{parent, child}).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	agentCorporate := standardModel.AgentCorporate{AgentID: lib.GenUUID(), CorporateID: &childID}
+	err = db.Create(&agentCorporate).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	path := "/api/v1/master/corporates/" + parentID.String() + "/dependencies"
+
+	// Case 1: one level, ignored child corporate is not walked
+	res, body, err := lib.GetTest(app, path, nil)
+	utils.AssertEqual(t, nil, err, "Must success")
+	utils.AssertEqual(t, 200, res.StatusCode, "validate status")
+	utils.AssertEqual(t, []interface{}{}, body["dependencies"], "validate dependencies")
+
+	// Case 2: transitive, same dependencies which block the delete
+	m.SetTransitive(true)
+	_, errDelete := m.CanDelete(context.Background(), "corporate", []uuid.UUID{parentID})
+	var errViolation *ProtectionViolationError
+	utils.AssertEqual(t, true, errors.As(errDelete, &errViolation), "validate delete is blocked")
+
+	res, body, err = lib.GetTest(app, path, nil)
+	utils.AssertEqual(t, nil, err, "Must success")
+	utils.AssertEqual(t, 200, res.StatusCode, "validate status")
+
+	dependencies, _ := body["dependencies"].([]interface{})
+	utils.AssertEqual(t, len(errViolation.Violations), len(dependencies), "validate dependencies")
+	dependency, _ := dependencies[0].(map[string]interface{})
+	utils.AssertEqual(t, "agent_corporate", dependency["used_by_table"], "validate table")
+	utils.AssertEqual(t, "corporate", dependency["referenced_table"], "validate referenced table")
+	utils.AssertEqual(t, float64(1), dependency["total"], "validate total")
+}
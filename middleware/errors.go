@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/terra-discover/bbcrs-helper-lib/pkg/lib"
+)
+
+// ErrRouterSourceEmpty - returned by ProtectRoute when MappingRoute has not been called
+var ErrRouterSourceEmpty = errors.New("router source is not found. Please Mapping Route first")
+
+// ErrModelSchemasEmpty - relation schema cannot be validated before model migrations are set
+var ErrModelSchemasEmpty = errors.New("model schemas is empty. Please Mapping Route with model migrations first")
+
+// ErrFiberAppEmpty - routes cannot be read from nil fiber app
+var ErrFiberAppEmpty = errors.New("fiber app is empty. Please register routes on fiber app first")
+
+// ErrFiberAppNotStarted - routes of mounted sub app are merged by fiber on startup, so they cannot be read before the app is started
+var ErrFiberAppNotStarted = errors.New("fiber app is not started. Please start the app first, ex: app.Handler(), to merge routes of mounted sub app")
+
+// ErrRouteFSEmpty - router files cannot be read from nil fs.FS
+var ErrRouteFSEmpty = errors.New("router file system is empty. Please use fs.FS which contains router files")
+
+// ErrDeleteLockUnsupported - referencing tables cannot be locked by DeleteProtected on the database
+var ErrDeleteLockUnsupported = errors.New("referencing tables cannot be locked on this database. Please use CanDelete with foreign key constraints")
+
+// RouteValidationError - router source does not match the router file or model migrations.
+// Only issues with SeverityError are listed, see ValidationReport for warnings.
+type RouteValidationError struct {
+	Issues []Issue
+}
+
+func (e *RouteValidationError) Error() string {
+	listMessage := make([]string, len(e.Issues))
+	for i := range e.Issues {
+		listMessage[i] = e.Issues[i].Message
+	}
+
+	return fmt.Sprintf("ERROR validateRouterSource: \n %s", strings.Join(listMessage, ";\n"))
+}
+
+// ProtectionQueryError - protection query of a dependent table is failed, so data protection cannot decide
+type ProtectionQueryError struct {
+	Table  string
+	Column string
+	Err    error
+}
+
+func (e *ProtectionQueryError) Error() string {
+	return fmt.Sprintf("protection query of %s.%s is failed: %s", e.Table, e.Column, e.Err.Error())
+}
+
+func (e *ProtectionQueryError) Unwrap() error {
+	return e.Err
+}
+
+// ProtectionLoadError - route maps cannot be loaded, ex: relation schema cannot be read or is not valid with model migrations,
+// so data protection cannot decide
+type ProtectionLoadError struct {
+	Err error
+}
+
+func (e *ProtectionLoadError) Error() string {
+	return fmt.Sprintf("route maps of data protection cannot be loaded: %s", e.Err.Error())
+}
+
+func (e *ProtectionLoadError) Unwrap() error {
+	return e.Err
+}
+
+// RequestError - request cannot be checked by data protection, ex: invalid request body or path module name.
+// Protect responds it with StatusCode and Message.
+type RequestError struct {
+	StatusCode int
+	Message    string
+	Err        error
+}
+
+func (e *RequestError) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+
+	return fmt.Sprintf("%s: %s", e.Message, e.Err.Error())
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// send - respond the request error, like lib.ErrorResponse
+func (e *RequestError) send(c *fiber.Ctx) error {
+	if e.StatusCode == fiber.StatusBadRequest {
+		return lib.ErrorBadRequest(c, e.Message)
+	}
+
+	return lib.ErrorInternal(c, e.Message)
+}
+
+// ProtectionViolationError - request or delete is rejected because data is still referenced by dependent tables.
+// Returned by Check, CanDelete, DeleteProtected and Plugin when the decision is not allowed.
+type ProtectionViolationError struct {
+	Table      string
+	IDs        []uuid.UUID
+	Violations []Violation
+	// Action - rejected action, ex: ActionDelete
+	Action string
+	// ChangedColumns - immutable columns changed by update request, only for ActionUpdate
+	ChangedColumns []string
+}
+
+// newViolationError - typed error of the decision which is not allowed
+func newViolationError(table string, ids []uuid.UUID, decision Decision) *ProtectionViolationError {
+	return &ProtectionViolationError{
+		Table:          table,
+		IDs:            ids,
+		Violations:     decision.Violations,
+		Action:         decision.Action,
+		ChangedColumns: decision.ChangedColumns,
+	}
+}
+
+func (e *ProtectionViolationError) Error() string {
+	switch e.Action {
+	case ActionUpdate:
+		return fmt.Sprintf("change %s of %s is not allowed, data is still referenced by %d table(s)", strings.Join(e.ChangedColumns, ", "), e.Table, len(e.Violations))
+	case ActionDeactivate:
+		return fmt.Sprintf("deactivate %s is not allowed, data is still referenced by %d table(s)", e.Table, len(e.Violations))
+	}
+
+	return fmt.Sprintf("delete %s is not allowed, data is still referenced by %d table(s)", e.Table, len(e.Violations))
+}
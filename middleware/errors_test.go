@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+func TestErrors(t *testing.T) {
+	db := testSupport__DBConnectTest()
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	// Case 1: route validation error
+	err := validateRoute(RouterSource{
+		UseMasterPattern("cities"): SourceRelation{Source: "city"},
+	}, []MappingRoute{{Method: "DELETE", Path: "/api/v1/master/countries/:id/translations"}}).Err()
+
+	var errValidation *RouteValidationError
+	utils.AssertEqual(t, true, errors.As(err, &errValidation), "validate RouteValidationError")
+	utils.AssertEqual(t, 1, len(errValidation.Issues), "validate issues")
+	utils.AssertEqual(t, UseMasterPattern("cities"), errValidation.Issues[0].Pattern, "validate issue pattern")
+
+	// Case 2: every error of middleware is joined
+	m := NewMiddleware(Environment{}, db)
+	m.setError(ErrRouterSourceEmpty)
+	m.setError(err)
+	utils.AssertEqual(t, true, errors.Is(m.Error, ErrRouterSourceEmpty), "validate joined sentinel")
+	utils.AssertEqual(t, true, errors.As(m.Error, &errValidation), "validate joined RouteValidationError")
+
+	// Case 3: model schemas is not set
+	_, err = m.CanDelete(context.Background(), "city", nil)
+	utils.AssertEqual(t, true, errors.Is(err, ErrRouterSourceEmpty), "validate router source empty")
+
+	err = modelSchemas{}.validateRouterMaps(routerMaps{"pattern": {"city": "country_id"}})
+	utils.AssertEqual(t, true, errors.Is(err, ErrModelSchemasEmpty), "validate model schemas empty")
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IgnoreAction - what to do with rows of an ignored relation after the source is deleted
+type IgnoreAction string
+
+const (
+	// IgnoreLeave - leave rows of ignored relation alone, default
+	IgnoreLeave IgnoreAction = ""
+	// IgnoreCascade - soft delete rows of ignored relation, table must have deleted_at
+	IgnoreCascade IgnoreAction = "cascade"
+	// IgnoreNullify - set referencing column of ignored relation to NULL
+	IgnoreNullify IgnoreAction = "nullify"
+)
+
+// ignoredRelation - relation of IgnoreRelation with its action
+type ignoredRelation struct {
+	table  string
+	column string
+	action IgnoreAction
+}
+
+// generateIgnoredRelations - ignored relations which have an action, keyed by source table.
+// Only tables of IgnoreRelation are used, routes sharing the same source are merged.
+func generateIgnoredRelations(db *gorm.DB, listRouterSource RouterSource) (ignoredRelations map[string][]ignoredRelation, err error) {
+	ignoredRelations = make(map[string][]ignoredRelation)
+
+	// Relations of ignored tables are generated as required relations
+	actionRouterSource := make(RouterSource)
+	for routePattern, sourceRelation := range listRouterSource {
+		listTable := []string{}
+		for _, table := range sourceRelation.IgnoreRelation {
+			if action := sourceRelation.IgnoreActions[table]; action != IgnoreLeave {
+				listTable = append(listTable, table)
+			}
+		}
+
+		if len(listTable) > 0 {
+			actionRouterSource[routePattern] = SourceRelation{
+				Source:           sourceRelation.Source,
+				RequiredRelation: listTable,
+			}
+		}
+	}
+
+	if len(actionRouterSource) == 0 {
+		return
+	}
+
+	actionRouteMaps, err := generateDeleteRouteMaps(db, actionRouterSource)
+	if err != nil {
+		return
+	}
+
+	// Sort route patterns, so merged relations are deterministic
+	listRoutePattern := make([]string, 0, len(actionRouteMaps))
+	for routePattern := range actionRouteMaps {
+		listRoutePattern = append(listRoutePattern, routePattern)
+	}
+	sort.Strings(listRoutePattern)
+
+	isAdded := make(map[string]bool)
+	for _, routePattern := range listRoutePattern {
+		sourceRelation := listRouterSource[routePattern]
+
+		listTable := make([]string, 0, len(actionRouteMaps[routePattern]))
+		for table := range actionRouteMaps[routePattern] {
+			listTable = append(listTable, table)
+		}
+		sort.Strings(listTable)
+
+		for _, table := range listTable {
+			key := sourceRelation.Source + "." + table
+			if isAdded[key] {
+				continue
+			}
+			isAdded[key] = true
+
+			ignoredRelations[sourceRelation.Source] = append(ignoredRelations[sourceRelation.Source], ignoredRelation{
+				table:  table,
+				column: actionRouteMaps[routePattern][table],
+				action: sourceRelation.IgnoreActions[table],
+			})
+		}
+	}
+
+	return
+}
+
+// applyIgnoreActions - cascade or nullify rows of ignored relations which reference the deleted ids.
+// Must be called with the transaction of the delete.
+func applyIgnoreActions(tx *gorm.DB, relations []ignoredRelation, ids []uuid.UUID) (err error) {
+	if len(ids) == 0 {
+		return
+	}
+
+	for _, relation := range relations {
+		query := tx.Session(&gorm.Session{NewDB: true}).
+			Table(relation.table).
+			Where(clause.IN{Column: clause.Column{Table: relation.table, Name: relation.column}, Values: toValues(ids)})
+
+		switch relation.action {
+		case IgnoreCascade:
+			err = query.
+				Where(clause.Eq{Column: clause.Column{Table: relation.table, Name: softDeleteColumn}, Value: nil}).
+				Update(softDeleteColumn, time.Now()).Error
+		case IgnoreNullify:
+			err = query.Update(relation.column, gorm.Expr("NULL")).Error
+		default:
+			continue
+		}
+
+		if err != nil {
+			err = fmt.Errorf("failed %s %s.%s: %s", relation.action, relation.table, relation.column, err.Error())
+			return
+		}
+	}
+
+	return
+}
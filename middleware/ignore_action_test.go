@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/google/uuid"
+	"github.com/terra-discover/bbcrs-helper-lib/pkg/lib"
+	standardModel "github.com/terra-discover/bbcrs-migration-lib/model"
+)
+
+func TestIgnoreActions(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	sqlDB, _ := db.DB()
+	// in memory database only live on single connection
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	listRelationSchema := []standardModel.RelationSchema{
+		{
+			ColumnSource: lib.Strptr("id"),
+			TableSource:  lib.Strptr("city"),
+			UsedByColumn: lib.Strptr("city_id"),
+			UsedByTable:  lib.Strptr("airport"),
+		},
+		{
+			ColumnSource: lib.Strptr("id"),
+			TableSource:  lib.Strptr("city"),
+			UsedByColumn: lib.Strptr("city_id"),
+			UsedByTable:  lib.Strptr("city_translation"),
+		},
+	}
+	for i := range listRelationSchema {
+		mod := db.Create(&listRelationSchema[i])
+		utils.AssertEqual(t, nil, mod.Error)
+	}
+
+	m := NewMiddleware(Environment{}, db)
+	m.ReplaceRouterSource(RouterSource{
+		UseMasterPattern("cities"): SourceRelation{
+			Source:         "city",
+			IgnoreRelation: []string{"airport", "city_translation"},
+			IgnoreActions: map[string]IgnoreAction{
+				"airport":          IgnoreNullify,
+				"city_translation": IgnoreCascade,
+			},
+		},
+	}).SetModelMigrations(testSupport__GetModelMigrations())
+	utils.AssertEqual(t, nil, m.Error, "validate err")
+
+	err := db.Use(m.Plugin())
+	utils.AssertEqual(t, nil, err, "use plugin")
+
+	createCity := func() (cityID, airportID, cityTranslationID uuid.UUID) {
+		cityID = uuid.New()
+		city := standardModel.City{}
+		city.ID = &cityID
+		city.CityCode = lib.Strptr(lib.RandomChars(6))
+		city.CityName = lib.Strptr(lib.RandomChars(6))
+		err := db.Create(&city).Error
+		utils.AssertEqual(t, nil, err, "mock data")
+
+		airport := standardModel.Airport{
+			AirportAPI: standardModel.AirportAPI{
+				AirportCode: lib.Strptr(lib.RandomChars(6)),
+				AirportName: lib.Strptr(lib.RandomChars(6)),
+				CityID:      &cityID,
+			},
+		}
+		err = db.Create(&airport).Error
+		utils.AssertEqual(t, nil, err, "mock data")
+
+		cityTranslation := standardModel.CityTranslation{
+			CityID: &cityID,
+			CityTranslationAPI: standardModel.CityTranslationAPI{
+				LanguageCode: lib.Strptr("id"),
+				CityName:     lib.Strptr(lib.RandomChars(10)),
+			},
+		}
+		err = db.Create(&cityTranslation).Error
+		utils.AssertEqual(t, nil, err, "mock data")
+
+		return cityID, *airport.ID, *cityTranslation.ID
+	}
+
+	validateIgnoredRelations := func(airportID, cityTranslationID uuid.UUID) {
+		airport := standardModel.Airport{}
+		err := db.Where("id = ?", airportID).Take(&airport).Error
+		utils.AssertEqual(t, nil, err, "validate airport is not deleted")
+		utils.AssertEqual(t, true, airport.CityID == nil, "validate airport is nullified")
+
+		var total int64
+		err = db.Model(&standardModel.CityTranslation{}).Where("id = ?", cityTranslationID).Count(&total).Error
+		utils.AssertEqual(t, nil, err, "count city translation")
+		utils.AssertEqual(t, int64(0), total, "validate city translation is cascaded")
+
+		err = db.Unscoped().Model(&standardModel.CityTranslation{}).Where("id = ?", cityTranslationID).Count(&total).Error
+		utils.AssertEqual(t, nil, err, "count city translation")
+		utils.AssertEqual(t, int64(1), total, "validate city translation is soft deleted")
+	}
+
+	// Case 1: delete by plugin
+	cityID, airportID, cityTranslationID := createCity()
+	err = db.Delete(&standardModel.City{}, "id = ?", cityID).Error
+	utils.AssertEqual(t, nil, err, "validate err")
+	validateIgnoredRelations(airportID, cityTranslationID)
+
+	// Case 2: delete by DeleteProtected
+	cityID, airportID, cityTranslationID = createCity()
+	_, err = m.DeleteProtected(context.Background(), db, &standardModel.City{}, []uuid.UUID{cityID})
+	utils.AssertEqual(t, nil, err, "validate err")
+	validateIgnoredRelations(airportID, cityTranslationID)
+
+	// Case 3: cascade table without deleted_at, error
+	err = generateAndValidateIgnoredRelations(m, RouterSource{
+		UseMasterPattern("cities"): SourceRelation{
+			Source:         "city",
+			IgnoreRelation: []string{"city_translation"},
+			IgnoreActions: map[string]IgnoreAction{
+				"city_translation": IgnoreCascade,
+			},
+		},
+	}, modelSchemas{
+		"city_translation": {"id": true, "city_id": true},
+	})
+	utils.AssertEqual(t, true, err != nil, "validate err")
+}
+
+func generateAndValidateIgnoredRelations(m *Middleware, routerSource RouterSource, schemas modelSchemas) (err error) {
+	ignoredRelations, err := generateIgnoredRelations(m.db, routerSource)
+	if err != nil {
+		return
+	}
+
+	return schemas.validateIgnoredRelations(ignoredRelations)
+}
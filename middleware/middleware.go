@@ -1,10 +1,12 @@
 package middleware
 
 import (
+	"context"
 	"errors"
-	"fmt"
+	"log"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -17,50 +19,255 @@ type Environment struct {
 // Implementing method chaining
 type Middleware struct {
 	Error error
+	// Report - issues of router source found by the last MappingRoute
+	Report ValidationReport
 
-	env Environment
-	db  *gorm.DB
+	env      Environment
+	db       *gorm.DB
+	registry *routerRegistry
+	cache    *routeMapsCache
+	config   protectionConfig
 }
 
 func NewMiddleware(env Environment, db *gorm.DB) (m *Middleware) {
 	m = new(Middleware)
 	m.setEnvironment(env)
 	m.setDB(db)
+	m.setRegistry(newRouterRegistry())
+	m.setCache(newRouteMapsCache())
 	return
 }
 
 type IMiddleware interface {
 	MappingRoute(newRouterSource RouterSource) *Middleware
+	MappingRouteInput(newRouterSource RouterSource, modelMigrations []interface{}, input RouteInput) *Middleware
 	ProtectRoute(c *fiber.Ctx) *Middleware
+	Protect(c *fiber.Ctx) error
+	Check(c *fiber.Ctx) (decision Decision, err error)
+	Dependencies(c *fiber.Ctx) error
+	CanDelete(ctx context.Context, tableName string, ids []uuid.UUID) (decision Decision, err error)
+	Plugin() *Plugin
+	DeleteProtected(ctx context.Context, tx *gorm.DB, model interface{}, ids []uuid.UUID) (decision Decision, err error)
+	ReplaceRouterSource(newRouterSource RouterSource) *Middleware
+	RemoveRouterSource(patterns ...string) *Middleware
+	ResetRouterSource() *Middleware
+	Refresh() *Middleware
+	SetProbeConcurrency(concurrency int) *Middleware
+	SetModelMigrations(modelMigrations []interface{}) *Middleware
+	SetFailurePolicy(policy FailurePolicy, callback ...FailureCallback) *Middleware
+	SetViolationSampleSize(size int) *Middleware
+	SetTenancy(tenantColumn string, resolver ...TenantResolver) *Middleware
+	SetTransitive(isTransitive bool) *Middleware
+	SetCoverage(coverage CoverageConfig) *Middleware
 
 	newSession()
 	isRouterSourceEmpty() bool
 	isErrorEmpty() bool
 	setEnvironment(newEnv Environment)
 	setDB(newDB *gorm.DB)
+	setRegistry(newRegistry *routerRegistry)
+	setCache(newCache *routeMapsCache)
+	warmCache()
+	setModelMigrations(modelMigrations []interface{}) (err error)
 	setError(err error)
 	clearError()
+	requestConfig(c *fiber.Ctx) (cfg protectionConfig)
+	contextConfig(ctx context.Context) (cfg protectionConfig)
 }
 
+// MappingRoute - add router source, then validate it with router file and model migrations.
+// Every issue is listed on Report, Error is set if router file cannot be read or Report has an error.
 func (m *Middleware) MappingRoute(newRouterSource RouterSource, modelMigrations []interface{}, routerFileDir, routerPrefix string) *Middleware {
+	return m.MappingRouteInput(newRouterSource, modelMigrations, RouteInputFromFile(routerFileDir, routerPrefix))
+}
+
+// MappingRouteInput - add router source, then validate it with routes of input and model migrations.
+// Ex: RouteInputFromApp validates routes registered on fiber app, without reading router file.
+func (m *Middleware) MappingRouteInput(newRouterSource RouterSource, modelMigrations []interface{}, input RouteInput) *Middleware {
 	m.newSession()
 
-	setDeleteRouterSource(newRouterSource)
-	err := validateRouterSource(m.db, modelMigrations, routerFileDir, routerPrefix)
+	m.registry.merge(newRouterSource)
+	m.cache.invalidate()
+	report, err := validateRouterSource(m.db, m.registry.get(), modelMigrations, input, m.config.coverage)
+	m.Report = report
+	m.setError(err)
+	m.setError(report.Err())
+
+	err = m.setModelMigrations(modelMigrations)
 	m.setError(err)
+
+	m.warmCache()
 	return m
 }
 
+// ProtectRoute - run data protection and save the result into Error.
+//
+// Deprecated: Error is shared by all requests, so the result can be overwritten by another request.
+// Use Protect instead.
 func (m *Middleware) ProtectRoute(c *fiber.Ctx) *Middleware {
 	m.newSession()
 
+	err := m.Protect(c)
+	m.setError(err)
+	return m
+}
+
+// Protect - request scoped data protection, safe to be called by concurrent requests.
+// Not allowed request will be responded with 405, otherwise continue to the next handler.
+func (m *Middleware) Protect(c *fiber.Ctx) error {
+	if m.isRouterSourceEmpty() {
+		return ErrRouterSourceEmpty
+	}
+
+	return runDataProtection(c, m.db, m.cache.loader(m.db, m.registry), m.requestConfig(c))
+}
+
+// Check - request scoped data protection without responding and without calling the next handler.
+// Not allowed decision is returned with *ProtectionViolationError.
+// Failed protection query is returned as *ProtectionQueryError, unless failure policy is FailOpen.
+// Request which cannot be checked is returned as *RequestError, ex: invalid request body.
+func (m *Middleware) Check(c *fiber.Ctx) (decision Decision, err error) {
+	if m.isRouterSourceEmpty() {
+		err = ErrRouterSourceEmpty
+		return
+	}
+
+	decision, errRequest, err := checkDataProtection(c, m.db.WithContext(c.UserContext()), m.cache.loader(m.db, m.registry), m.requestConfig(c))
+	if err != nil {
+		return
+	}
+
+	if errRequest != nil {
+		err = errRequest
+		return
+	}
+
+	return
+}
+
+// CanDelete - decide whether ids of source table are allowed to be deleted, usable outside HTTP request,
+// ex: background job, gRPC handler or CLI script. Relations of every route with the same source are protected.
+// Table which is not a source of router source is allowed, not allowed decision is returned with *ProtectionViolationError.
+// Failed protection query is returned as *ProtectionQueryError, unless failure policy is FailOpen.
+func (m *Middleware) CanDelete(ctx context.Context, tableName string, ids []uuid.UUID) (decision Decision, err error) {
 	if m.isRouterSourceEmpty() {
-		m.setError(errors.New("router source is not found. Please Mapping Route first"))
+		err = ErrRouterSourceEmpty
+		return
+	}
+
+	return canDelete(m.db.WithContext(ctx), m.cache.loader(m.db, m.registry), m.contextConfig(ctx), tableName, ids)
+}
+
+// ReplaceRouterSource - add router source of this middleware, existing pattern will be replaced
+func (m *Middleware) ReplaceRouterSource(newRouterSource RouterSource) *Middleware {
+	m.newSession()
+
+	m.registry.replace(newRouterSource)
+	m.cache.invalidate()
+	m.warmCache()
+	return m
+}
+
+// RemoveRouterSource - remove router source of this middleware by patterns
+func (m *Middleware) RemoveRouterSource(patterns ...string) *Middleware {
+	m.newSession()
+
+	m.registry.remove(patterns...)
+	m.cache.invalidate()
+	m.warmCache()
+	return m
+}
+
+// ResetRouterSource - remove all router source of this middleware
+func (m *Middleware) ResetRouterSource() *Middleware {
+	m.newSession()
+
+	m.registry.reset()
+	m.cache.invalidate()
+	m.warmCache()
+	return m
+}
+
+// Refresh - invalidate cached route maps, then reload relation schema.
+// If reload is failed, relation schema will be reloaded on the next protected request.
+// Must be called after relation schema is changed outside of Migration.MigrateRelation.
+func (m *Middleware) Refresh() *Middleware {
+	m.newSession()
+
+	m.cache.invalidate()
+	m.warmCache()
+	return m
+}
+
+// SetProbeConcurrency - maximum dependent tables probed in parallel for one request.
+// Default 1, tables are probed one by one and stop at the first referencing table.
+// Inside a transaction, ex: DeleteProtected and Plugin, tables are always probed one by one.
+// Must be called before serving requests.
+func (m *Middleware) SetProbeConcurrency(concurrency int) *Middleware {
+	m.newSession()
+
+	m.config.probeConcurrency = concurrency
+	return m
+}
+
+// SetFailurePolicy - decide what to do with the request when protection query is failed.
+// Default FailClosed. Callback is required by FailCallback.
+// Must be called before serving requests.
+func (m *Middleware) SetFailurePolicy(policy FailurePolicy, callback ...FailureCallback) *Middleware {
+	m.newSession()
+
+	if policy == FailCallback && (len(callback) == 0 || callback[0] == nil) {
+		m.setError(errors.New("callback is required by FailCallback policy"))
 		return m
 	}
 
-	err := runDataProtection(c, m.db)
+	m.config.failurePolicy = policy
+	m.config.failureCallback = nil
+	if len(callback) > 0 {
+		m.config.failureCallback = callback[0]
+	}
+	return m
+}
+
+// SetViolationSampleSize - maximum referencing ids of each dependent table in 405 response.
+// Default 0, only total references are responded.
+// Must be called before serving requests.
+func (m *Middleware) SetViolationSampleSize(size int) *Middleware {
+	m.newSession()
+
+	m.config.violationSampleSize = size
+	return m
+}
+
+// SetTransitive - walk ignored and cascaded relations of delete through relation schema.
+// Delete is blocked if a live row outside the walked chain still references the chain,
+// ex: country is blocked by transaction referencing an ignored city of the country.
+// Default false, only one level is checked. Must be called before serving requests.
+func (m *Middleware) SetTransitive(isTransitive bool) *Middleware {
+	m.newSession()
+
+	m.config.isTransitive = isTransitive
+	return m
+}
+
+// SetCoverage - set allowlist and severity of DELETE routes which are not covered by router source.
+// Default uncovered route is reported as warning. Must be called before MappingRoute.
+func (m *Middleware) SetCoverage(coverage CoverageConfig) *Middleware {
+	m.newSession()
+
+	m.config.coverage = coverage
+	return m
+}
+
+// SetModelMigrations - set model migrations to validate table and column of relation schema.
+// Already called by MappingRoute, only needed if router source is set by ReplaceRouterSource.
+func (m *Middleware) SetModelMigrations(modelMigrations []interface{}) *Middleware {
+	m.newSession()
+
+	err := m.setModelMigrations(modelMigrations)
 	m.setError(err)
+
+	m.warmCache()
 	return m
 }
 
@@ -69,7 +276,7 @@ func (m *Middleware) newSession() {
 }
 
 func (m *Middleware) isRouterSourceEmpty() bool {
-	mapRouterSource := getDeleteRouterSource()
+	mapRouterSource := m.registry.get()
 	return len(mapRouterSource) == 0
 }
 
@@ -85,11 +292,42 @@ func (m *Middleware) setDB(newDB *gorm.DB) {
 	m.db = newDB
 }
 
+func (m *Middleware) setRegistry(newRegistry *routerRegistry) {
+	m.registry = newRegistry
+}
+
+func (m *Middleware) setCache(newCache *routeMapsCache) {
+	m.cache = newCache
+}
+
+// warmCache - compile route maps ahead of time by m.db, so the cache is not built inside a transaction of the caller,
+// ex: by Plugin or DeleteProtected. Failure is only logged, the cache is built again on the next protected request.
+func (m *Middleware) warmCache() {
+	if m.db == nil || m.isRouterSourceEmpty() {
+		return
+	}
+
+	if _, err := m.cache.get(m.db, m.registry); err != nil {
+		log.Println("WARNING warmCache:", err.Error())
+	}
+}
+
+// setModelMigrations - identifiers of relation schema will be validated by model migrations
+func (m *Middleware) setModelMigrations(modelMigrations []interface{}) (err error) {
+	schemas, fieldTypes, err := parseModelSchemas(m.db, modelMigrations)
+	if err != nil {
+		return
+	}
+
+	m.cache.setSchemas(schemas, fieldTypes)
+	return
+}
+
 func (m *Middleware) setError(newError error) {
 	if m.isErrorEmpty() {
 		m.Error = newError
 	} else if newError != nil {
-		m.Error = fmt.Errorf("%v; %w", m.Error, newError)
+		m.Error = errors.Join(m.Error, newError)
 	}
 }
 
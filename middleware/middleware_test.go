@@ -0,0 +1,542 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/google/uuid"
+	"github.com/terra-discover/bbcrs-helper-lib/pkg/lib"
+	standardModel "github.com/terra-discover/bbcrs-migration-lib/model"
+)
+
+func TestMiddleware_Protect(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	sqlDB, _ := db.DB()
+	// in memory database only live on single connection
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	m := NewMiddleware(Environment{}, db)
+
+	app := fiber.New()
+	app.Use(m.Protect)
+	app.Delete("/api/v1/master/agent-corporates/:id", func(c *fiber.Ctx) error {
+		return c.Status(200).JSON(fiber.Map{"status": 200})
+	})
+
+	// Case 1: router source empty, error
+	res, _, err := lib.DeleteTest(app, "/api/v1/master/agent-corporates/"+uuid.New().String(), nil)
+	utils.AssertEqual(t, nil, err, "Must success")
+	utils.AssertEqual(t, 500, res.StatusCode, "Must be error")
+
+	var errCheck error
+	appCheck := fiber.New()
+	appCheck.Delete("/*", func(c *fiber.Ctx) error {
+		_, errCheck = m.Check(c)
+		return nil
+	})
+	_, _, err = lib.DeleteTest(appCheck, "/api/v1/master/agent-corporates/"+uuid.New().String(), nil)
+	utils.AssertEqual(t, nil, err, "Must success")
+	utils.AssertEqual(t, true, errors.Is(errCheck, ErrRouterSourceEmpty), "validate err")
+
+	relationSchema := standardModel.RelationSchema{
+		ColumnSource: lib.Strptr("id"),
+		TableSource:  lib.Strptr("corporate"),
+		UsedByColumn: lib.Strptr("corporate_id"),
+		UsedByTable:  lib.Strptr("agent_corporate"),
+	}
+	mod := db.Create(&relationSchema)
+	utils.AssertEqual(t, nil, mod.Error)
+
+	m.ReplaceRouterSource(RouterSource{
+		UseMasterPattern("agent-corporates"): SourceRelation{
+			Source: "corporate",
+		},
+	}).SetModelMigrations(testSupport__GetModelMigrations())
+	utils.AssertEqual(t, nil, m.Error, "validate err")
+
+	usedID := uuid.New()
+	agentCorporate := standardModel.AgentCorporate{}
+	agentCorporate.CorporateID = &usedID
+	agentCorporate.AgentID = &usedID
+	mod = db.Create(&agentCorporate)
+	utils.AssertEqual(t, nil, mod.Error)
+
+	// Case 2: 405 response list the blocking tables
+	m.SetViolationSampleSize(5)
+	res, body, err := lib.DeleteTest(app, "/api/v1/master/agent-corporates/"+usedID.String(), nil)
+	utils.AssertEqual(t, nil, err, "Must success")
+	utils.AssertEqual(t, 405, res.StatusCode, "validate status")
+
+	violations, _ := body["violations"].([]interface{})
+	utils.AssertEqual(t, 1, len(violations), "validate violations")
+	violation, _ := violations[0].(map[string]interface{})
+	utils.AssertEqual(t, "agent_corporate", violation["used_by_table"], "validate table")
+	utils.AssertEqual(t, "corporate_id", violation["used_by_column"], "validate column")
+	utils.AssertEqual(t, float64(1), violation["total"], "validate total")
+	utils.AssertEqual(t, []interface{}{agentCorporate.ID.String()}, violation["reference_ids"], "validate reference ids")
+
+	// Check return not allowed decision as typed error
+	_, _, err = lib.DeleteTest(appCheck, "/api/v1/master/agent-corporates/"+usedID.String(), nil)
+	utils.AssertEqual(t, nil, err, "Must success")
+
+	var errViolation *ProtectionViolationError
+	utils.AssertEqual(t, true, errors.As(errCheck, &errViolation), "validate err")
+	utils.AssertEqual(t, "corporate", errViolation.Table, "validate violation table")
+	utils.AssertEqual(t, []uuid.UUID{usedID}, errViolation.IDs, "validate violation ids")
+	utils.AssertEqual(t, "agent_corporate", errViolation.Violations[0].UsedByTable, "validate violations")
+
+	// Case 3: concurrent requests, every request get its own decision
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(isUsed bool) {
+			defer wg.Done()
+
+			id, wantStatus := uuid.New(), 200
+			if isUsed {
+				id, wantStatus = usedID, 405
+			}
+
+			res, _, err := lib.DeleteTest(app, "/api/v1/master/agent-corporates/"+id.String(), nil)
+			utils.AssertEqual(t, nil, err, "Must success")
+			utils.AssertEqual(t, wantStatus, res.StatusCode, "validate status")
+		}(i%2 == 0)
+	}
+	wg.Wait()
+}
+
+func TestMiddleware_SetFailurePolicy(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	sqlDB, _ := db.DB()
+	// in memory database only live on single connection
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	relationSchema := standardModel.RelationSchema{
+		ColumnSource: lib.Strptr("id"),
+		TableSource:  lib.Strptr("corporate"),
+		UsedByColumn: lib.Strptr("corporate_id"),
+		UsedByTable:  lib.Strptr("agent_corporate"),
+	}
+	mod := db.Create(&relationSchema)
+	utils.AssertEqual(t, nil, mod.Error)
+
+	// Dependent table is missing, so protection query is failed
+	err := db.Migrator().DropTable(&standardModel.AgentCorporate{})
+	utils.AssertEqual(t, nil, err, "drop table")
+
+	m := NewMiddleware(Environment{}, db)
+	m.ReplaceRouterSource(RouterSource{
+		UseMasterPattern("agent-corporates"): SourceRelation{
+			Source: "corporate",
+		},
+	}).SetModelMigrations(testSupport__GetModelMigrations())
+	utils.AssertEqual(t, nil, m.Error, "validate err")
+
+	app := fiber.New()
+	app.Use(m.Protect)
+	app.Delete("/api/v1/master/agent-corporates/:id", func(c *fiber.Ctx) error {
+		return c.Status(200).JSON(fiber.Map{"status": 200})
+	})
+
+	path := "/api/v1/master/agent-corporates/" + uuid.New().String()
+
+	// Callback is required by FailCallback
+	m.SetFailurePolicy(FailCallback)
+	utils.AssertEqual(t, true, m.Error != nil, "validate err")
+
+	tests := []struct {
+		name       string
+		policy     FailurePolicy
+		callback   FailureCallback
+		wantStatus int
+	}{
+		{
+			name:       "default fail closed",
+			policy:     FailClosed,
+			wantStatus: 503,
+		},
+		{
+			name:       "fail closed internal",
+			policy:     FailClosedInternal,
+			wantStatus: 500,
+		},
+		{
+			name:       "fail open",
+			policy:     FailOpen,
+			wantStatus: 200,
+		},
+		{
+			name:   "fail callback",
+			policy: FailCallback,
+			callback: func(c *fiber.Ctx, err error) error {
+				var errQuery *ProtectionQueryError
+				if errors.As(err, &errQuery) && errQuery.Table == "agent_corporate" {
+					return c.SendStatus(fiber.StatusConflict)
+				}
+				return c.SendStatus(fiber.StatusTeapot)
+			},
+			wantStatus: 409,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.SetFailurePolicy(tt.policy, tt.callback)
+			utils.AssertEqual(t, nil, m.Error, "validate err")
+
+			res, _, err := lib.DeleteTest(app, path, nil)
+			utils.AssertEqual(t, nil, err, "Must success")
+			utils.AssertEqual(t, tt.wantStatus, res.StatusCode, "validate status")
+		})
+	}
+
+	// Check surface the typed error
+	m.SetFailurePolicy(FailClosed)
+	var errCheck error
+	appCheck := fiber.New()
+	appCheck.Delete("/*", func(c *fiber.Ctx) error {
+		_, errCheck = m.Check(c)
+		return nil
+	})
+	_, _, err = lib.DeleteTest(appCheck, path, nil)
+	utils.AssertEqual(t, nil, err, "Must success")
+
+	var errQuery *ProtectionQueryError
+	utils.AssertEqual(t, true, errors.As(errCheck, &errQuery), "validate err")
+	utils.AssertEqual(t, "corporate_id", errQuery.Column, "validate column")
+}
+
+func TestMiddleware_SetFailurePolicy_loadFailure(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	sqlDB, _ := db.DB()
+	// in memory database only live on single connection
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	m := NewMiddleware(Environment{}, db)
+	m.ReplaceRouterSource(RouterSource{
+		UseMasterPattern("cities"): SourceRelation{
+			Source: "city",
+		},
+	})
+
+	// Relation schema cannot be read, so route maps cannot be loaded
+	err := db.Migrator().DropTable(&standardModel.RelationSchema{})
+	utils.AssertEqual(t, nil, err, "drop table")
+	m.Refresh()
+
+	app := fiber.New()
+	app.Get("/api/v1/master/cities/:id/dependencies", m.Dependencies)
+	app.Use(m.Protect)
+	app.Delete("/api/v1/master/cities/:id", func(c *fiber.Ctx) error {
+		return c.Status(200).JSON(fiber.Map{"status": 200})
+	})
+
+	path := "/api/v1/master/cities/" + uuid.New().String()
+
+	tests := []struct {
+		name       string
+		policy     FailurePolicy
+		callback   FailureCallback
+		wantStatus int
+	}{
+		{
+			name:       "default fail closed",
+			policy:     FailClosed,
+			wantStatus: 503,
+		},
+		{
+			name:       "fail open",
+			policy:     FailOpen,
+			wantStatus: 200,
+		},
+		{
+			name:   "fail callback",
+			policy: FailCallback,
+			callback: func(c *fiber.Ctx, err error) error {
+				var errLoad *ProtectionLoadError
+				if errors.As(err, &errLoad) {
+					return c.SendStatus(fiber.StatusConflict)
+				}
+				return c.SendStatus(fiber.StatusTeapot)
+			},
+			wantStatus: 409,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.SetFailurePolicy(tt.policy, tt.callback)
+			utils.AssertEqual(t, nil, m.Error, "validate err")
+
+			res, _, err := lib.DeleteTest(app, path, nil)
+			utils.AssertEqual(t, nil, err, "Must success")
+			utils.AssertEqual(t, tt.wantStatus, res.StatusCode, "validate status")
+		})
+	}
+
+	// Preview has nothing to allow, fail open is responded like fail closed
+	m.SetFailurePolicy(FailOpen)
+	res, _, err := lib.GetTest(app, path+"/dependencies", nil)
+	utils.AssertEqual(t, nil, err, "Must success")
+	utils.AssertEqual(t, 503, res.StatusCode, "validate dependencies status")
+}
+
+func TestMiddleware_MappingRouteInput_invalidateCache(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	sqlDB, _ := db.DB()
+	// in memory database only live on single connection
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	relationSchema := standardModel.RelationSchema{
+		ColumnSource: lib.Strptr("id"),
+		TableSource:  lib.Strptr("country"),
+		UsedByColumn: lib.Strptr("country_id"),
+		UsedByTable:  lib.Strptr("city"),
+	}
+	mod := db.Create(&relationSchema)
+	utils.AssertEqual(t, nil, mod.Error)
+
+	m := NewMiddleware(Environment{}, db)
+	m.ReplaceRouterSource(RouterSource{
+		UseMasterPattern("cities"): SourceRelation{
+			Source: "city",
+		},
+	}).SetModelMigrations(testSupport__GetModelMigrations())
+	utils.AssertEqual(t, nil, m.Error, "validate err")
+	utils.AssertEqual(t, true, m.cache.isValid, "validate warm cache")
+
+	// Invalid model migrations keep the previous schemas, merged pattern is still protected
+	fsys := fstest.MapFS{
+		"router.json": {Data: []byte(`[{"method": "DELETE", "path": "/api/v1/master/countries/:id"}]`)},
+	}
+	m.MappingRouteInput(RouterSource{
+		UseMasterPattern("countries"): SourceRelation{
+			Source: "country",
+		},
+	}, []interface{}{nil}, RouteInputFromFS(fsys, RouteFileEntry{Pattern: "router.json"}))
+	utils.AssertEqual(t, true, m.Error != nil, "validate err")
+
+	routes, err := m.cache.get(m.db, m.registry)
+	utils.AssertEqual(t, nil, err, "validate err")
+
+	isProtected := false
+	for _, route := range routes.deleteRoutes {
+		if route.pattern == UseMasterPattern("countries") {
+			isProtected = true
+		}
+	}
+	utils.AssertEqual(t, true, isProtected, "validate merged pattern is protected")
+}
+
+func TestMiddleware_CanDelete(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	m := NewMiddleware(Environment{}, db)
+
+	// Case 1: router source empty, error
+	_, err := m.CanDelete(context.Background(), "city", []uuid.UUID{uuid.New()})
+	utils.AssertEqual(t, true, errors.Is(err, ErrRouterSourceEmpty), "validate err")
+
+	listRelationSchema := []standardModel.RelationSchema{
+		{
+			ColumnSource: lib.Strptr("id"),
+			TableSource:  lib.Strptr("city"),
+			UsedByColumn: lib.Strptr("city_id"),
+			UsedByTable:  lib.Strptr("airport"),
+		},
+		{
+			ColumnSource: lib.Strptr("id"),
+			TableSource:  lib.Strptr("city"),
+			UsedByColumn: lib.Strptr("city_id"),
+			UsedByTable:  lib.Strptr("city_translation"),
+		},
+	}
+	for i := range listRelationSchema {
+		mod := db.Create(&listRelationSchema[i])
+		utils.AssertEqual(t, nil, mod.Error)
+	}
+
+	// Relations of every route with the same source are protected
+	m.ReplaceRouterSource(RouterSource{
+		UseMasterPattern("cities"): SourceRelation{
+			Source:         "city",
+			IgnoreRelation: []string{"city_translation"},
+		},
+		".*/city-translations/cities/([^/]+)$": SourceRelation{
+			Source:           "city",
+			RequiredRelation: []string{"city_translation"},
+		},
+	}).SetModelMigrations(testSupport__GetModelMigrations())
+	utils.AssertEqual(t, nil, m.Error, "validate err")
+
+	cityID := uuid.New()
+	city := standardModel.City{}
+	city.ID = &cityID
+	city.CityCode = lib.Strptr(lib.RandomChars(6))
+	city.CityName = lib.Strptr(lib.RandomChars(6))
+	err = db.Create(&city).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	cityTranslation := standardModel.CityTranslation{
+		CityID: city.ID,
+		CityTranslationAPI: standardModel.CityTranslationAPI{
+			LanguageCode: lib.Strptr("id"),
+			CityName:     lib.Strptr(lib.RandomChars(10)),
+		},
+	}
+	err = db.Create(&cityTranslation).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	tests := []struct {
+		name            string
+		tableName       string
+		ids             []uuid.UUID
+		wantIsProtected bool
+		wantIsAllowed   bool
+		wantViolations  int
+	}{
+		{
+			name:            "referenced, not allowed",
+			tableName:       "city",
+			ids:             []uuid.UUID{uuid.New(), cityID},
+			wantIsProtected: true,
+			wantIsAllowed:   false,
+			wantViolations:  1,
+		},
+		{
+			name:            "not referenced, allowed",
+			tableName:       "city",
+			ids:             []uuid.UUID{uuid.New()},
+			wantIsProtected: true,
+			wantIsAllowed:   true,
+		},
+		{
+			name:            "table is not a source, allowed",
+			tableName:       "country",
+			ids:             []uuid.UUID{cityID},
+			wantIsProtected: false,
+			wantIsAllowed:   true,
+		},
+		{
+			name:            "empty ids, allowed",
+			tableName:       "city",
+			wantIsProtected: false,
+			wantIsAllowed:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := m.CanDelete(context.Background(), tt.tableName, tt.ids)
+			var errViolation *ProtectionViolationError
+			utils.AssertEqual(t, !tt.wantIsAllowed, errors.As(err, &errViolation), "validate err")
+			if !tt.wantIsAllowed {
+				utils.AssertEqual(t, tt.tableName, errViolation.Table, "validate violation table")
+				utils.AssertEqual(t, tt.ids, errViolation.IDs, "validate violation ids")
+				utils.AssertEqual(t, ActionDelete, errViolation.Action, "validate violation action")
+			}
+			utils.AssertEqual(t, tt.wantIsProtected, decision.IsProtected, "validate is protected")
+			utils.AssertEqual(t, tt.wantIsAllowed, decision.IsAllowed, "validate is allowed")
+			utils.AssertEqual(t, tt.wantViolations, len(decision.Violations), "validate violations")
+		})
+	}
+}
+
+func TestMiddleware_SetTenancy(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	relationSchema := standardModel.RelationSchema{
+		ColumnSource: lib.Strptr("id"),
+		TableSource:  lib.Strptr("corporate"),
+		UsedByColumn: lib.Strptr("corporate_id"),
+		UsedByTable:  lib.Strptr("agent_corporate"),
+	}
+	mod := db.Create(&relationSchema)
+	utils.AssertEqual(t, nil, mod.Error)
+
+	agentID, otherAgentID, corporateID := uuid.New(), uuid.New(), uuid.New()
+	agentCorporate := standardModel.AgentCorporate{}
+	agentCorporate.AgentID = &agentID
+	agentCorporate.CorporateID = &corporateID
+	mod = db.Create(&agentCorporate)
+	utils.AssertEqual(t, nil, mod.Error)
+
+	m := NewMiddleware(Environment{AgentID: otherAgentID.String()}, db)
+	m.ReplaceRouterSource(RouterSource{
+		UseMasterPattern("corporates"): SourceRelation{
+			Source: "corporate",
+		},
+	}).SetModelMigrations(testSupport__GetModelMigrations())
+	utils.AssertEqual(t, nil, m.Error, "validate err")
+
+	app := fiber.New()
+	app.Use(m.Protect)
+	app.Delete("/api/v1/master/corporates/:id", func(c *fiber.Ctx) error {
+		return c.Status(200).JSON(fiber.Map{"status": 200})
+	})
+	path := "/api/v1/master/corporates/" + corporateID.String()
+
+	// Case 1: tenancy mode disabled, reference of any agent blocks deletion
+	var errViolation *ProtectionViolationError
+	decision, err := m.CanDelete(context.Background(), "corporate", []uuid.UUID{corporateID})
+	utils.AssertEqual(t, true, errors.As(err, &errViolation), "validate err")
+	utils.AssertEqual(t, false, decision.IsAllowed, "validate is allowed")
+
+	// Case 2: tenancy mode, tenant from environment, reference of other agent does not block deletion
+	m.SetTenancy("agent_id")
+	decision, err = m.CanDelete(context.Background(), "corporate", []uuid.UUID{corporateID})
+	utils.AssertEqual(t, nil, err, "validate err")
+	utils.AssertEqual(t, true, decision.IsAllowed, "validate is allowed")
+
+	res, _, err := lib.DeleteTest(app, path, nil)
+	utils.AssertEqual(t, nil, err, "Must success")
+	utils.AssertEqual(t, 200, res.StatusCode, "validate status")
+
+	// Case 3: tenancy mode, tenant from context
+	decision, err = m.CanDelete(WithTenant(context.Background(), agentID.String()), "corporate", []uuid.UUID{corporateID})
+	utils.AssertEqual(t, true, errors.As(err, &errViolation), "validate err")
+	utils.AssertEqual(t, false, decision.IsAllowed, "validate is allowed")
+
+	// Case 4: tenancy mode, tenant from resolver
+	m.SetTenancy("agent_id", func(c *fiber.Ctx) string {
+		return c.Get("X-Agent-ID")
+	})
+	res, _, err = lib.DeleteTest(app, path, map[string]string{"X-Agent-ID": agentID.String()})
+	utils.AssertEqual(t, nil, err, "Must success")
+	utils.AssertEqual(t, 405, res.StatusCode, "validate status")
+
+	res, _, err = lib.DeleteTest(app, path, map[string]string{"X-Agent-ID": otherAgentID.String()})
+	utils.AssertEqual(t, nil, err, "Must success")
+	utils.AssertEqual(t, 200, res.StatusCode, "validate status")
+
+	// Case 5: tenancy mode disabled again
+	m.SetTenancy("")
+	res, _, err = lib.DeleteTest(app, path, map[string]string{"X-Agent-ID": otherAgentID.String()})
+	utils.AssertEqual(t, nil, err, "Must success")
+	utils.AssertEqual(t, 405, res.StatusCode, "validate status")
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const softDeleteColumn = "deleted_at"
+
+// modelSchemas - list column of each table parsed from model migrations
+// Format:
+//
+//	"table_name": {
+//	    "column_name": true,
+//	}
+//
+// Notes: Table and column from relation_schema must be listed here before used on protection query
+type modelSchemas map[string]map[string]bool
+
+// modelFieldTypes - data type of each column parsed from model migrations, ex: "city": {"is_active": schema.Bool}
+type modelFieldTypes map[string]map[string]schema.DataType
+
+// parseModelSchemas - parse GORM schema of model migrations without querying database
+func parseModelSchemas(db *gorm.DB, modelMigrations []interface{}) (schemas modelSchemas, fieldTypes modelFieldTypes, err error) {
+	schemas = make(modelSchemas)
+	fieldTypes = make(modelFieldTypes)
+
+	for idx, model := range modelMigrations {
+		if model == nil {
+			err = fmt.Errorf("parseModelSchemas: model on index %d is nil", idx)
+			return
+		}
+
+		stmt := &gorm.Statement{DB: db}
+		if errParse := stmt.Parse(model); errParse != nil {
+			err = fmt.Errorf("parseModelSchemas: failed parse model on index %d, message: %s", idx, errParse.Error())
+			return
+		}
+
+		columns := make(map[string]bool)
+		dataTypes := make(map[string]schema.DataType)
+		for _, column := range stmt.Schema.DBNames {
+			columns[column] = true
+			dataTypes[column] = stmt.Schema.FieldsByDBName[column].DataType
+		}
+		schemas[stmt.Schema.Table] = columns
+		fieldTypes[stmt.Schema.Table] = dataTypes
+	}
+
+	return
+}
+
+// validate - make sure table and column are listed on model migrations
+func (ms modelSchemas) validate(table, column string) (err error) {
+	if len(ms) == 0 {
+		err = ErrModelSchemasEmpty
+		return
+	}
+
+	columns, ok := ms[table]
+	if !ok {
+		err = fmt.Errorf("table %s is not listed on model migrations", table)
+		return
+	}
+
+	if !columns[column] {
+		err = fmt.Errorf("column %s is not listed on table %s of model migrations", column, table)
+		return
+	}
+
+	return
+}
+
+// hasColumn - check whether table has column
+func (ms modelSchemas) hasColumn(table, column string) bool {
+	return ms[table][column]
+}
+
+// validateRouterMaps - make sure all table and column of router maps are listed on model migrations
+func (ms modelSchemas) validateRouterMaps(rMaps routerMaps) (err error) {
+	for routePattern, rMap := range rMaps {
+		for table, column := range rMap {
+			if errValidate := ms.validate(table, column); errValidate != nil {
+				err = fmt.Errorf("invalid relation schema of pattern %s: %w", routePattern, errValidate)
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// validateImmutableColumns - make sure source table and immutable columns are listed on model migrations
+func (ms modelSchemas) validateImmutableColumns(routerSource RouterSource) (err error) {
+	for routePattern, sourceRelation := range routerSource {
+		if len(sourceRelation.ImmutableColumns) == 0 {
+			continue
+		}
+
+		for _, column := range append([]string{"id"}, sourceRelation.ImmutableColumns...) {
+			if errValidate := ms.validate(sourceRelation.Source, column); errValidate != nil {
+				err = fmt.Errorf("invalid immutable columns of pattern %s: %w", routePattern, errValidate)
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// validateIgnoredRelations - make sure ignored relations are listed on model migrations,
+// cascade is only allowed on table with soft delete column
+func (ms modelSchemas) validateIgnoredRelations(ignoredRelations map[string][]ignoredRelation) (err error) {
+	for source, relations := range ignoredRelations {
+		for _, relation := range relations {
+			if errValidate := ms.validate(relation.table, relation.column); errValidate != nil {
+				err = fmt.Errorf("invalid ignore actions of source %s: %w", source, errValidate)
+				return
+			}
+
+			if relation.action == IgnoreCascade && !ms.hasColumn(relation.table, softDeleteColumn) {
+				err = fmt.Errorf("invalid ignore actions of source %s: table %s has no %s to cascade", source, relation.table, softDeleteColumn)
+				return
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2/utils"
+	standardModel "github.com/terra-discover/bbcrs-migration-lib/model"
+	"gorm.io/gorm/schema"
+)
+
+func Test_parseModelSchemas(t *testing.T) {
+	db := testSupport__DBConnectTest()
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	// Case 1: valid models, not error
+	schemas, fieldTypes, err := parseModelSchemas(db, []interface{}{
+		&standardModel.City{},
+		&standardModel.Country{},
+	})
+	utils.AssertEqual(t, nil, err, "validate err")
+	utils.AssertEqual(t, 2, len(schemas), "validate length")
+	utils.AssertEqual(t, true, schemas.hasColumn("city", "country_id"), "validate column")
+	utils.AssertEqual(t, true, schemas.hasColumn("city", softDeleteColumn), "validate soft delete column")
+	utils.AssertEqual(t, schema.String, fieldTypes["city"]["city_code"], "validate data type")
+	utils.AssertEqual(t, schema.Time, fieldTypes["city"][softDeleteColumn], "validate data type")
+
+	// Case 2: nil model, error
+	_, _, err = parseModelSchemas(db, []interface{}{nil})
+	utils.AssertEqual(t, true, err != nil, "validate err")
+
+	// Case 3: invalid model, error
+	_, _, err = parseModelSchemas(db, []interface{}{"city"})
+	utils.AssertEqual(t, true, err != nil, "validate err")
+}
+
+func Test_modelSchemas_validate(t *testing.T) {
+	schemas := modelSchemas{
+		"city": {
+			"id":         true,
+			"country_id": true,
+		},
+	}
+
+	tests := []struct {
+		name    string
+		schemas modelSchemas
+		table   string
+		column  string
+		wantErr bool
+	}{
+		{
+			name:    "listed table and column, not error",
+			schemas: schemas,
+			table:   "city",
+			column:  "country_id",
+			wantErr: false,
+		},
+		{
+			name:    "table not listed, error",
+			schemas: schemas,
+			table:   "country",
+			column:  "country_id",
+			wantErr: true,
+		},
+		{
+			name:    "column not listed, error",
+			schemas: schemas,
+			table:   "city",
+			column:  "state_id",
+			wantErr: true,
+		},
+		{
+			name:    "empty schemas, error",
+			schemas: modelSchemas{},
+			table:   "city",
+			column:  "country_id",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.schemas.validate(tt.table, tt.column)
+			utils.AssertEqual(t, tt.wantErr, err != nil, "validate err")
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIDocument - part of OpenAPI 3 document which describes routes, JSON is parsed as YAML
+type openAPIDocument struct {
+	OpenAPI string                          `yaml:"openapi"`
+	Servers []openAPIServer                 `yaml:"servers"`
+	Paths   map[string]map[string]yaml.Node `yaml:"paths"`
+}
+
+type openAPIServer struct {
+	URL       string `yaml:"url"`
+	Variables map[string]struct {
+		Default string `yaml:"default"`
+	} `yaml:"variables"`
+}
+
+// openAPIOperation - operation of path item, only servers are used
+type openAPIOperation struct {
+	Servers []openAPIServer `yaml:"servers"`
+}
+
+// openAPIPathParam - path template parameter, ex: {id}
+var openAPIPathParam = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// isOpenAPIDocument - json router file is an OpenAPI document if it is an object instead of list of route
+func isOpenAPIDocument(bte []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(bte), []byte("{"))
+}
+
+/*
+mappingRouteOpenAPIFile - OpenAPI 3 document in JSON or YAML.
+Path of every operation is prefixed by path of servers URL, the nearest servers of operation, path item, or document is used.
+Path template is normalized to fiber path, so RouterSource is validated like router file.
+Format:
+
+	servers:
+	  - url: https://example.com/api/v1
+	paths:
+	  /cities/{id}:
+	    delete:
+	      operationId: deleteCity
+
+Result: DELETE /api/v1/cities/:id
+*/
+func mappingRouteOpenAPIFile(bte []byte, methodCheck method) (listRoute []MappingRoute, err error) {
+	doc := openAPIDocument{}
+	if errUnmarshal := yaml.Unmarshal(bte, &doc); errUnmarshal != nil {
+		err = fmt.Errorf("mappingRouteOpenAPIFile cannot unmarshal openapi: %s", errUnmarshal)
+		return
+	}
+
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		err = fmt.Errorf("mappingRouteOpenAPIFile: openapi version %q is not supported, please use OpenAPI 3", doc.OpenAPI)
+		return
+	}
+
+	// Sort paths, so routes are deterministic
+	listPath := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		listPath = append(listPath, path)
+	}
+	sort.Strings(listPath)
+
+	isExist := make(map[string]bool)
+	for _, path := range listPath {
+		pathItem := doc.Paths[path]
+
+		operationNode, ok := pathItem[strings.ToLower(methodCheck.String())]
+		if !ok {
+			continue
+		}
+
+		// The nearest servers override servers of the parent
+		servers := doc.Servers
+		pathServers, operation := []openAPIServer{}, openAPIOperation{}
+		if serversNode, isServers := pathItem["servers"]; isServers {
+			if errDecode := serversNode.Decode(&pathServers); errDecode != nil {
+				err = fmt.Errorf("mappingRouteOpenAPIFile cannot decode servers of path %s: %s", path, errDecode)
+				return
+			}
+		}
+		if errDecode := operationNode.Decode(&operation); errDecode != nil {
+			err = fmt.Errorf("mappingRouteOpenAPIFile cannot decode operation of path %s: %s", path, errDecode)
+			return
+		}
+		if len(pathServers) > 0 {
+			servers = pathServers
+		}
+		if len(operation.Servers) > 0 {
+			servers = operation.Servers
+		}
+
+		listPrefix, errPrefix := openAPIServerPrefixes(servers)
+		if errPrefix != nil {
+			err = fmt.Errorf("mappingRouteOpenAPIFile path %s: %s", path, errPrefix)
+			return
+		}
+
+		for _, prefix := range listPrefix {
+			fullPath := joinRoutePath(prefix, normalizeOpenAPIPath(path))
+			if isExist[fullPath] {
+				continue
+			}
+
+			isExist[fullPath] = true
+			listRoute = append(listRoute, MappingRoute{
+				Method: methodCheck.String(),
+				Path:   fullPath,
+			})
+		}
+	}
+
+	return
+}
+
+// openAPIServerPrefixes - path of every server URL, variables are replaced by their default value.
+// Without server, path is not prefixed.
+func openAPIServerPrefixes(servers []openAPIServer) (listPrefix []string, err error) {
+	if len(servers) == 0 {
+		return []string{""}, nil
+	}
+
+	isExist := make(map[string]bool)
+	for _, server := range servers {
+		rawURL := server.URL
+		for name, variable := range server.Variables {
+			rawURL = strings.ReplaceAll(rawURL, "{"+name+"}", variable.Default)
+		}
+
+		serverURL, errParse := url.Parse(rawURL)
+		if errParse != nil {
+			err = fmt.Errorf("invalid server url %s: %s", server.URL, errParse)
+			return
+		}
+
+		prefix := strings.TrimRight(serverURL.Path, "/")
+		if !isExist[prefix] {
+			isExist[prefix] = true
+			listPrefix = append(listPrefix, prefix)
+		}
+	}
+
+	return
+}
+
+// normalizeOpenAPIPath - path template to fiber path, ex: /cities/{id} to /cities/:id
+func normalizeOpenAPIPath(path string) string {
+	return openAPIPathParam.ReplaceAllString(path, ":$1")
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+func Test_mappingRouteOpenAPIFile(t *testing.T) {
+	yamlDocument := `
+openapi: 3.0.3
+servers:
+  - url: https://{host}/{basePath}
+    variables:
+      host:
+        default: example.com
+      basePath:
+        default: api/v1
+paths:
+  /cities/{id}:
+    get:
+      operationId: getCity
+    delete:
+      operationId: deleteCity
+  /countries/{countryId}/translations/{id}:
+    servers:
+      - url: /api/v2
+    delete:
+      operationId: deleteCountryTranslation
+  /airports/{id}:
+    delete:
+      operationId: deleteAirport
+      servers:
+        - url: http://localhost:3000/master/
+        - url: /master
+`
+	jsonDocument := `{
+		"openapi": "3.1.0",
+		"paths": {
+			"/cities/{id}": {
+				"delete": {"operationId": "deleteCity"}
+			}
+		}
+	}`
+
+	dir := t.TempDir()
+	writeFile := func(name, src string) string {
+		fileName := filepath.Join(dir, name)
+		err := os.WriteFile(fileName, []byte(src), 0644)
+		utils.AssertEqual(t, nil, err, "write file")
+		return fileName
+	}
+
+	tests := []struct {
+		name          string
+		methodCheck   method
+		routerFileDir string
+		routerPrefix  string
+		wantListRoute []MappingRoute
+		wantErr       bool
+	}{
+		{
+			name:          "yaml, servers of document, path item, and operation",
+			methodCheck:   DeleteMethod,
+			routerFileDir: writeFile("openapi.yaml", yamlDocument),
+			wantListRoute: []MappingRoute{
+				{Method: DeleteMethod.String(), Path: "/master/airports/:id"},
+				{Method: DeleteMethod.String(), Path: "/api/v1/cities/:id"},
+				{Method: DeleteMethod.String(), Path: "/api/v2/countries/:countryId/translations/:id"},
+			},
+		},
+		{
+			name:          "json object, without servers, router prefix",
+			methodCheck:   DeleteMethod,
+			routerFileDir: writeFile("openapi.json", jsonDocument),
+			routerPrefix:  "/my-endpoint",
+			wantListRoute: []MappingRoute{
+				{Method: DeleteMethod.String(), Path: "/my-endpoint/cities/:id"},
+			},
+		},
+		{
+			name:          "swagger 2, error",
+			methodCheck:   DeleteMethod,
+			routerFileDir: writeFile("swagger.yml", "swagger: \"2.0\"\npaths: {}\n"),
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotListRoute, err := mappingRoute(tt.methodCheck, tt.routerFileDir, tt.routerPrefix)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("mappingRoute() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(gotListRoute, tt.wantListRoute) {
+				t.Errorf("mappingRoute() = %v, want %v", gotListRoute, tt.wantListRoute)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package middleware
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+)
+
+// maxPatternSamples - maximum paths synthesized from a pattern, alternations are expanded until the limit
+const maxPatternSamples = 16
+
+// patternSampleID - id of synthesized path, the first capture group must be able to capture it
+const patternSampleID = "6f1c2a9e-3b4d-4e5f-8a7b-9c0d1e2f3a4b"
+
+// lintRouterSource - static analysis of router source patterns, without routes of the router file.
+// Pattern without capture group is reported as an error by validateRoute.
+// Report:
+//
+//	the first capture group cannot capture id of data
+//	extra capture groups, only the first group is used as id
+//	pattern is not anchored at the end of path
+//	two patterns match a synthesized path
+func lintRouterSource(routerSource RouterSource) (issues []Issue) {
+	// Sort patterns, so overlapped pairs are deterministic
+	listPattern := make([]string, 0, len(routerSource))
+	for routePattern := range routerSource {
+		listPattern = append(listPattern, routePattern)
+	}
+	sort.Strings(listPattern)
+
+	listRegex := []*regexp.Regexp{}
+	listSample := [][]string{}
+
+loopListPattern:
+	for _, routePattern := range listPattern {
+		// Invalid pattern is reported by validateRoute
+		pattern, errCompile := regexp.Compile(routePattern)
+		if errCompile != nil {
+			continue loopListPattern
+		}
+		re, errParse := syntax.Parse(routePattern, syntax.Perl)
+		if errParse != nil {
+			continue loopListPattern
+		}
+		re = re.Simplify()
+
+		samples := matchingSamples(pattern, synthesizePaths(re, false))
+		idSamples := matchingSamples(pattern, synthesizePaths(re, true))
+		suggestion := suggestMasterPattern(routePattern, samples)
+
+		if !isEndAnchored(re) {
+			issues = append(issues, Issue{
+				Code:       IssuePatternUnanchored,
+				Severity:   SeverityWarning,
+				Pattern:    routePattern,
+				Message:    fmt.Sprintf("pattern %s is not anchored at the end of path, longer paths are matched too", routePattern),
+				Suggestion: "End the pattern with $" + suggestion,
+			})
+		}
+
+		switch {
+		case pattern.NumSubexp() == 0:
+			// Reported by validateRoute
+		case len(samples) > 0 && len(idSamples) == 0:
+			issues = append(issues, Issue{
+				Code:       IssuePatternIDGroupInvalid,
+				Severity:   SeverityError,
+				Pattern:    routePattern,
+				Message:    fmt.Sprintf("the first capture group of pattern %s cannot capture the id, data is not protected", routePattern),
+				Suggestion: "Capture the id by the first group, use (?:...) for other groups" + suggestion,
+			})
+		case pattern.NumSubexp() > 1:
+			issues = append(issues, Issue{
+				Code:       IssuePatternExtraGroup,
+				Severity:   SeverityWarning,
+				Pattern:    routePattern,
+				Message:    fmt.Sprintf("pattern %s has %d capture groups, only the first group is used as the id", routePattern, pattern.NumSubexp()),
+				Suggestion: "Use (?:...) for groups other than the id" + suggestion,
+			})
+		}
+
+		if len(idSamples) > 0 {
+			samples = idSamples
+		}
+		listRegex = append(listRegex, pattern)
+		listSample = append(listSample, samples)
+	}
+
+	// Overlapped patterns are reported on both patterns, like ambiguous route
+	for i := range listRegex {
+		for j := i + 1; j < len(listRegex); j++ {
+			path, isOverlap := overlapSample(listRegex[j], listSample[i])
+			if !isOverlap {
+				path, isOverlap = overlapSample(listRegex[i], listSample[j])
+			}
+			if !isOverlap {
+				continue
+			}
+
+			for _, pair := range [][2]string{{listRegex[i].String(), listRegex[j].String()}, {listRegex[j].String(), listRegex[i].String()}} {
+				issues = append(issues, Issue{
+					Code:       IssuePatternOverlap,
+					Severity:   SeverityWarning,
+					Pattern:    pair[0],
+					Route:      path,
+					Message:    fmt.Sprintf("pattern %s overlaps pattern %s, both match path %s", pair[0], pair[1], path),
+					Suggestion: "Make the patterns more specific, ex: anchor the start with ^ or use the full prefix",
+				})
+			}
+		}
+	}
+
+	return
+}
+
+// isEndAnchored - every alternative of the pattern ends with $ or \z
+func isEndAnchored(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpEndText:
+		return true
+	case syntax.OpCapture:
+		return isEndAnchored(re.Sub[0])
+	case syntax.OpConcat:
+		return len(re.Sub) > 0 && isEndAnchored(re.Sub[len(re.Sub)-1])
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			if !isEndAnchored(sub) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// synthesizePaths - sample paths of a pattern, optional part is included once and repetition is the minimum.
+// If isID, the first capture group is filled by patternSampleID.
+func synthesizePaths(re *syntax.Regexp, isID bool) (samples []string) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return []string{string(re.Rune)}
+	case syntax.OpCharClass:
+		if len(re.Rune) == 0 {
+			return nil
+		}
+		return []string{string(pickClassRune(re.Rune))}
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return []string{"a"}
+	case syntax.OpEmptyMatch, syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return []string{""}
+	case syntax.OpCapture:
+		if isID && re.Cap == 1 {
+			return []string{patternSampleID}
+		}
+		return synthesizePaths(re.Sub[0], isID)
+	case syntax.OpStar:
+		return []string{""}
+	case syntax.OpPlus:
+		return synthesizePaths(re.Sub[0], isID)
+	case syntax.OpQuest:
+		return limitSamples(append(synthesizePaths(re.Sub[0], isID), ""))
+	case syntax.OpRepeat:
+		if re.Min == 0 {
+			return []string{""}
+		}
+		for _, sample := range synthesizePaths(re.Sub[0], isID) {
+			samples = append(samples, strings.Repeat(sample, re.Min))
+		}
+		return
+	case syntax.OpConcat:
+		samples = []string{""}
+		for _, sub := range re.Sub {
+			subSamples := synthesizePaths(sub, isID)
+			combined := []string{}
+			for _, prefix := range samples {
+				for _, suffix := range subSamples {
+					combined = append(combined, prefix+suffix)
+				}
+			}
+			samples = limitSamples(combined)
+		}
+		return
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			samples = append(samples, synthesizePaths(sub, isID)...)
+		}
+		return limitSamples(samples)
+	}
+	return nil
+}
+
+// pickClassRune - readable rune of character class, ex: a for [^/]
+func pickClassRune(ranges []rune) rune {
+	for _, r := range []rune{'a', 'x', '1', '-'} {
+		for i := 0; i+1 < len(ranges); i += 2 {
+			if ranges[i] <= r && r <= ranges[i+1] {
+				return r
+			}
+		}
+	}
+	return ranges[0]
+}
+
+func limitSamples(samples []string) []string {
+	if len(samples) > maxPatternSamples {
+		return samples[:maxPatternSamples]
+	}
+	return samples
+}
+
+// matchingSamples - synthesized paths which are matched by the pattern, ex: word boundary may not be satisfied
+func matchingSamples(pattern *regexp.Regexp, samples []string) (listPath []string) {
+	for _, sample := range samples {
+		if pattern.MatchString(sample) {
+			listPath = append(listPath, sample)
+		}
+	}
+	return
+}
+
+// overlapSample - the first sample path which is matched by the pattern
+func overlapSample(pattern *regexp.Regexp, samples []string) (path string, isOverlap bool) {
+	for _, sample := range samples {
+		if pattern.MatchString(sample) {
+			return sample, true
+		}
+	}
+	return
+}
+
+// suggestMasterPattern - UseMasterPattern of module of the pattern, if it matches every sample path of the pattern
+func suggestMasterPattern(routePattern string, samples []string) (suggestion string) {
+	module := getRoutePatternModule(routePattern)
+	if module == "" || len(samples) == 0 {
+		return
+	}
+
+	masterPattern := regexp.MustCompile(UseMasterPattern(module))
+	for _, sample := range samples {
+		if !masterPattern.MatchString(sample) {
+			return
+		}
+	}
+
+	return fmt.Sprintf(", ex: UseMasterPattern(%q)", module)
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+func Test_lintRouterSource(t *testing.T) {
+	tests := []struct {
+		name           string
+		routerSource   RouterSource
+		wantCodes      []string
+		wantSuggestion string
+	}{
+		{
+			name: "master pattern, no issue",
+			routerSource: RouterSource{
+				UseMasterPattern("cities"):    SourceRelation{Source: "city"},
+				UseMasterPattern("countries"): SourceRelation{Source: "country"},
+			},
+			wantCodes: []string{},
+		},
+		{
+			name: "unanchored pattern, suggest master pattern",
+			routerSource: RouterSource{
+				".*/api/v1/master/cities/([^/]+)": SourceRelation{Source: "city"},
+			},
+			wantCodes:      []string{IssuePatternUnanchored},
+			wantSuggestion: `UseMasterPattern("cities")`,
+		},
+		{
+			name: "group before the id, the first group is not the id",
+			routerSource: RouterSource{
+				".*/api/(v1|v2)/master/cities/([^/]+)$": SourceRelation{Source: "city"},
+			},
+			wantCodes: []string{IssuePatternIDGroupInvalid},
+		},
+		{
+			name: "extra capture group after the id",
+			routerSource: RouterSource{
+				`.*/cities/([^/]+)(/)?$`: SourceRelation{Source: "city"},
+			},
+			wantCodes: []string{IssuePatternExtraGroup},
+		},
+		{
+			name: "the first group cannot capture uuid",
+			routerSource: RouterSource{
+				".*/cities/([0-9]+)$": SourceRelation{Source: "city"},
+			},
+			wantCodes: []string{IssuePatternIDGroupInvalid},
+		},
+		{
+			name: "overlapped patterns for synthesized path",
+			routerSource: RouterSource{
+				UseMasterPattern("cities"): SourceRelation{Source: "city"},
+				".*/cities/([^/]+)$":       SourceRelation{Source: "city"},
+			},
+			wantCodes: []string{IssuePatternOverlap, IssuePatternOverlap},
+		},
+		{
+			name: "invalid pattern and pattern without group are reported by validateRoute",
+			routerSource: RouterSource{
+				".*/cities/(":       SourceRelation{Source: "city"},
+				".*/countries/:id$": SourceRelation{Source: "country"},
+			},
+			wantCodes: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := lintRouterSource(tt.routerSource)
+
+			codes := []string{}
+			for _, issue := range issues {
+				codes = append(codes, issue.Code)
+			}
+			utils.AssertEqual(t, tt.wantCodes, codes, "validate codes")
+
+			if tt.wantSuggestion != "" {
+				utils.AssertEqual(t, true, strings.Contains(issues[0].Suggestion, tt.wantSuggestion), issues[0].Suggestion)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Instance keys of ignored relations, set by beforeDelete to be applied by afterDelete
+const (
+	ignoredRelationsKey = "route_protection:ignored_relations"
+	deletedIdsKey       = "route_protection:deleted_ids"
+)
+
+// Plugin - gorm plugin which protects every delete of source table,
+// including delete which does not come from a mapped DELETE route.
+// Relations are taken from router source by table name, so RequiredRelation and IgnoreRelation are honoured.
+// Rows of ignored relations are cascaded or nullified by SourceRelation.IgnoreActions after the delete.
+//
+// Example:
+//
+//	db.Use(m.Plugin())
+type Plugin struct {
+	middleware *Middleware
+}
+
+// Plugin - make gorm plugin of this middleware
+func (m *Middleware) Plugin() *Plugin {
+	return &Plugin{middleware: m}
+}
+
+func (p *Plugin) Name() string {
+	return "route_protection"
+}
+
+func (p *Plugin) Initialize(db *gorm.DB) (err error) {
+	err = db.Callback().Delete().Before("gorm:delete").Register("route_protection:before_delete", p.beforeDelete)
+	if err != nil {
+		return
+	}
+
+	return db.Callback().Delete().After("gorm:delete").Register("route_protection:after_delete", p.afterDelete)
+}
+
+// beforeDelete - reject delete with *ProtectionViolationError when live references exist
+func (p *Plugin) beforeDelete(tx *gorm.DB) {
+	m := p.middleware
+	if tx.Error != nil || tx.Statement.Schema == nil || m.isRouterSourceEmpty() {
+		return
+	}
+
+	if isSkipped, _ := tx.Get(skipPluginKey); isSkipped == true {
+		return
+	}
+
+	// Route maps are built by m.db outside of the transaction, a failed statement of cache build aborts
+	// the transaction on some databases, ex: postgres. The cache is usually built by MappingRoute already.
+	cfg := m.contextConfig(tx.Statement.Context)
+	routes, errLoad := m.cache.get(m.db, m.registry)
+	if errLoad != nil {
+		if _, err := cfg.onLoadFailure(errLoad); err != nil {
+			tx.AddError(err)
+		}
+		return
+	}
+
+	// Query on the same connection of tx, so uncommitted rows of transaction are visible.
+	// Dependent tables are probed one by one on the transaction, a cancelled parallel probe would abort it.
+	db := tx.Session(&gorm.Session{NewDB: true})
+
+	tableName := tx.Statement.Table
+	rmap, isProtected := routes.isProtectedTable(tableName, cfg)
+	relations := routes.ignoredTables[tableName]
+	if !isProtected && len(relations) == 0 {
+		return
+	}
+
+	ids, err := findDeletedIds(db, tx.Statement)
+	if err != nil {
+		log.Println("ERROR Plugin beforeDelete:", err.Error())
+		tx.AddError(err)
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	// Ignored relations are applied after delete
+	if len(relations) > 0 {
+		tx.InstanceSet(ignoredRelationsKey, relations)
+		tx.InstanceSet(deletedIdsKey, ids)
+	}
+
+	if !isProtected {
+		return
+	}
+
+	decision, err := decideDelete(db, routes, tableName, rmap, ids, cfg)
+	if err != nil {
+		tx.AddError(err)
+		return
+	}
+
+	if !decision.IsAllowed {
+		tx.AddError(newViolationError(tableName, ids, decision))
+	}
+}
+
+// afterDelete - cascade or nullify rows of ignored relations, in the transaction of the delete.
+// Delete without transaction (ex: SkipDefaultTransaction) is not atomic.
+func (p *Plugin) afterDelete(tx *gorm.DB) {
+	if tx.Error != nil {
+		return
+	}
+
+	relations, _ := tx.InstanceGet(ignoredRelationsKey)
+	ids, _ := tx.InstanceGet(deletedIdsKey)
+
+	listRelation, _ := relations.([]ignoredRelation)
+	listID, _ := ids.([]uuid.UUID)
+	if err := applyIgnoreActions(tx, listRelation, listID); err != nil {
+		log.Println("ERROR Plugin afterDelete:", err.Error())
+		tx.AddError(err)
+	}
+}
+
+// findDeletedIds - find ids which will be deleted by statement,
+// using the same conditions of gorm delete callback
+func findDeletedIds(db *gorm.DB, stmt *gorm.Statement) (ids []uuid.UUID, err error) {
+	primaryField := stmt.Schema.PrioritizedPrimaryField
+	if primaryField == nil {
+		err = fmt.Errorf("primary key of table %s is not found", stmt.Table)
+		return
+	}
+
+	query := db.Model(reflect.New(stmt.Schema.ModelType).Interface())
+	if stmt.Unscoped {
+		query = query.Unscoped()
+	}
+
+	if where, ok := stmt.Clauses["WHERE"]; ok {
+		query = query.Clauses(where.Expression)
+	}
+
+	// Primary keys of deleted value and model
+	listValue := []reflect.Value{stmt.ReflectValue}
+	if stmt.Model != nil && stmt.Dest != stmt.Model {
+		listValue = append(listValue, reflect.ValueOf(stmt.Model))
+	}
+	for _, value := range listValue {
+		_, queryValues := schema.GetIdentityFieldValuesMap(stmt.Context, value, stmt.Schema.PrimaryFields)
+		column, values := schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, queryValues)
+		if len(values) > 0 {
+			query = query.Clauses(clause.Where{Exprs: []clause.Expression{clause.IN{Column: column, Values: values}}})
+		}
+	}
+
+	// Delete without condition will be rejected by gorm
+	if _, ok := query.Statement.Clauses["WHERE"]; !ok && !stmt.AllowGlobalUpdate {
+		return
+	}
+
+	err = query.Pluck(primaryField.DBName, &ids).Error
+	return
+}
@@ -0,0 +1,249 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/google/uuid"
+	"github.com/terra-discover/bbcrs-helper-lib/pkg/lib"
+	standardModel "github.com/terra-discover/bbcrs-migration-lib/model"
+	"gorm.io/gorm"
+)
+
+func TestPlugin(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	sqlDB, _ := db.DB()
+	// in memory database only live on single connection
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	relationSchema := standardModel.RelationSchema{
+		ColumnSource: lib.Strptr("id"),
+		TableSource:  lib.Strptr("city"),
+		UsedByColumn: lib.Strptr("city_id"),
+		UsedByTable:  lib.Strptr("airport"),
+	}
+	mod := db.Create(&relationSchema)
+	utils.AssertEqual(t, nil, mod.Error)
+
+	m := NewMiddleware(Environment{}, db)
+	err := db.Use(m.Plugin())
+	utils.AssertEqual(t, nil, err, "use plugin")
+
+	createCity := func() standardModel.City {
+		cityID := uuid.New()
+		city := standardModel.City{}
+		city.ID = &cityID
+		city.CityCode = lib.Strptr(lib.RandomChars(6))
+		city.CityName = lib.Strptr(lib.RandomChars(6))
+		err := db.Create(&city).Error
+		utils.AssertEqual(t, nil, err, "mock data")
+		return city
+	}
+
+	// Case 1: router source empty, not protected
+	city := createCity()
+	err = db.Delete(&city).Error
+	utils.AssertEqual(t, nil, err, "validate err")
+
+	m.ReplaceRouterSource(RouterSource{
+		UseMasterPattern("cities"): SourceRelation{
+			Source: "city",
+		},
+	}).SetModelMigrations(testSupport__GetModelMigrations())
+	utils.AssertEqual(t, nil, m.Error, "validate err")
+	// Route maps are built ahead of time, not inside the transaction of delete
+	utils.AssertEqual(t, true, m.cache.isValid, "validate warm cache")
+
+	usedCity := createCity()
+	airport := standardModel.Airport{
+		AirportAPI: standardModel.AirportAPI{
+			AirportCode: lib.Strptr(lib.RandomChars(6)),
+			AirportName: lib.Strptr(lib.RandomChars(6)),
+			CityID:      usedCity.ID,
+		},
+	}
+	err = db.Create(&airport).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	tests := []struct {
+		name    string
+		delete  func(db *gorm.DB) error
+		wantErr bool
+	}{
+		{
+			name: "delete by value, referenced, rejected",
+			delete: func(db *gorm.DB) error {
+				return db.Delete(&usedCity).Error
+			},
+			wantErr: true,
+		},
+		{
+			name: "delete by primary key, referenced, rejected",
+			delete: func(db *gorm.DB) error {
+				return db.Delete(&standardModel.City{}, "id = ?", usedCity.ID).Error
+			},
+			wantErr: true,
+		},
+		{
+			name: "delete by condition, referenced, rejected",
+			delete: func(db *gorm.DB) error {
+				return db.Where("city_code = ?", usedCity.CityCode).Delete(&standardModel.City{}).Error
+			},
+			wantErr: true,
+		},
+		{
+			name: "delete in transaction, referenced, rejected",
+			delete: func(db *gorm.DB) error {
+				return db.Transaction(func(tx *gorm.DB) error {
+					return tx.Delete(&usedCity).Error
+				})
+			},
+			wantErr: true,
+		},
+		{
+			name: "delete by value, not referenced, allowed",
+			delete: func(db *gorm.DB) error {
+				city := createCity()
+				return db.Delete(&city).Error
+			},
+		},
+		{
+			name: "table is not a source, allowed",
+			delete: func(db *gorm.DB) error {
+				return db.Delete(&airport).Error
+			},
+		},
+		{
+			name: "reference is deleted, allowed",
+			delete: func(db *gorm.DB) error {
+				return db.Delete(&usedCity).Error
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.delete(db)
+
+			var errViolation *ProtectionViolationError
+			utils.AssertEqual(t, tt.wantErr, errors.As(err, &errViolation), "validate err")
+			if tt.wantErr {
+				utils.AssertEqual(t, "city", errViolation.Table, "validate table")
+				utils.AssertEqual(t, []uuid.UUID{*usedCity.ID}, errViolation.IDs, "validate ids")
+				utils.AssertEqual(t, "airport", errViolation.Violations[0].UsedByTable, "validate violations")
+			} else {
+				utils.AssertEqual(t, nil, err, "validate err")
+			}
+		})
+	}
+
+	// Last case is allowed
+	var total int64
+	err = db.Model(&standardModel.City{}).Where("id = ?", usedCity.ID).Count(&total).Error
+	utils.AssertEqual(t, nil, err, "count city")
+	utils.AssertEqual(t, int64(0), total, "validate city is deleted at the end")
+}
+
+func TestPlugin_probeConcurrency(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	sqlDB, _ := db.DB()
+	// in memory database only live on single connection
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	listRelationSchema := []standardModel.RelationSchema{
+		{
+			ColumnSource: lib.Strptr("id"),
+			TableSource:  lib.Strptr("city"),
+			UsedByColumn: lib.Strptr("city_id"),
+			UsedByTable:  lib.Strptr("airport"),
+		},
+		{
+			ColumnSource: lib.Strptr("id"),
+			TableSource:  lib.Strptr("city"),
+			UsedByColumn: lib.Strptr("city_id"),
+			UsedByTable:  lib.Strptr("city_translation"),
+		},
+	}
+	mod := db.Create(&listRelationSchema)
+	utils.AssertEqual(t, nil, mod.Error)
+
+	m := NewMiddleware(Environment{}, db)
+	m.ReplaceRouterSource(RouterSource{
+		UseMasterPattern("cities"): SourceRelation{
+			Source: "city",
+		},
+	}).SetModelMigrations(testSupport__GetModelMigrations()).SetProbeConcurrency(4)
+	utils.AssertEqual(t, nil, m.Error, "validate err")
+
+	err := db.Use(m.Plugin())
+	utils.AssertEqual(t, nil, err, "use plugin")
+
+	// Record probed tables, in the order of probe
+	var (
+		mutex       sync.Mutex
+		probeTables []string
+	)
+	err = db.Callback().Row().Before("gorm:row").Register("test:probe", func(tx *gorm.DB) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if sql := tx.Statement.SQL.String(); strings.Contains(sql, "LIMIT 1") {
+			probeTables = append(probeTables, strings.Split(strings.Split(sql, "FROM `")[1], "`")[0])
+		}
+	})
+	utils.AssertEqual(t, nil, err, "register callback")
+
+	createCity := func() standardModel.City {
+		cityID := uuid.New()
+		city := standardModel.City{}
+		city.ID = &cityID
+		city.CityCode = lib.Strptr(lib.RandomChars(6))
+		city.CityName = lib.Strptr(lib.RandomChars(6))
+		err := db.Create(&city).Error
+		utils.AssertEqual(t, nil, err, "mock data")
+		return city
+	}
+
+	// Reference is found on the first table, airport
+	usedCity := createCity()
+	airport := standardModel.Airport{
+		AirportAPI: standardModel.AirportAPI{
+			AirportCode: lib.Strptr(lib.RandomChars(6)),
+			AirportName: lib.Strptr(lib.RandomChars(6)),
+			CityID:      usedCity.ID,
+		},
+	}
+	err = db.Create(&airport).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	unusedCity := createCity()
+
+	// Rejected delete does not poison the caller transaction
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var errViolation *ProtectionViolationError
+		errDelete := tx.Delete(&usedCity).Error
+		utils.AssertEqual(t, true, errors.As(errDelete, &errViolation), "validate violation")
+
+		// Tables are probed one by one inside transaction, city_translation is not probed after the first reference
+		mutex.Lock()
+		utils.AssertEqual(t, []string{"airport"}, probeTables, "validate sequential probe")
+		mutex.Unlock()
+
+		return tx.Delete(&unusedCity).Error
+	})
+	utils.AssertEqual(t, nil, err, "validate err")
+
+	var total int64
+	err = db.Model(&standardModel.City{}).Where("id = ?", unusedCity.ID).Count(&total).Error
+	utils.AssertEqual(t, nil, err, "count city")
+	utils.AssertEqual(t, int64(0), total, "validate city is deleted")
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"fmt"
+	"io/fs"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RouteInput - registered routes which are validated with router source, see RouteInputFromFile, RouteInputFromFS and RouteInputFromApp
+type RouteInput interface {
+	listRoute(methodCheck method) (listRoute []MappingRoute, err error)
+}
+
+// routeFileInput - routes of router file, for offline validation
+type routeFileInput struct {
+	routerFileDir string
+	routerPrefix  string
+}
+
+// RouteInputFromFile - routes parsed from router file, Go router package, or json file.
+// routerPrefix is prepended to every path, can be empty string if router file already describe the prefix.
+// Go routes are only collected from fiber routers, ex: *fiber.App, fiber.Router parameter or result of Group.
+func RouteInputFromFile(routerFileDir, routerPrefix string) RouteInput {
+	return routeFileInput{routerFileDir: routerFileDir, routerPrefix: routerPrefix}
+}
+
+func (input routeFileInput) listRoute(methodCheck method) (listRoute []MappingRoute, err error) {
+	return mappingRoute(methodCheck, input.routerFileDir, input.routerPrefix)
+}
+
+// RouteFileEntry - router files matched by glob pattern of fs.FS, ex: "router/*.go", with prefix of every path.
+// Matched directory is parsed as Go router package.
+type RouteFileEntry struct {
+	Pattern string
+	Prefix  string
+}
+
+// routeFSInput - routes of router files inside fs.FS, ex: embed.FS
+type routeFSInput struct {
+	fsys    fs.FS
+	entries []RouteFileEntry
+}
+
+// RouteInputFromFS - routes of every router file matched by entries, merged before validation.
+// Example:
+//
+//	//go:embed router
+//	var routerFS embed.FS
+//
+//	middleware.RouteInputFromFS(routerFS,
+//		middleware.RouteFileEntry{Pattern: "router/master/*.go"},
+//		middleware.RouteFileEntry{Pattern: "router/openapi/*.yaml", Prefix: "/api/v1"},
+//	)
+func RouteInputFromFS(fsys fs.FS, entries ...RouteFileEntry) RouteInput {
+	return routeFSInput{fsys: fsys, entries: entries}
+}
+
+func (input routeFSInput) listRoute(methodCheck method) (listRoute []MappingRoute, err error) {
+	if input.fsys == nil {
+		err = ErrRouteFSEmpty
+		return
+	}
+
+	isExist := make(map[string]bool)
+	for _, entry := range input.entries {
+		listName, errGlob := fs.Glob(input.fsys, entry.Pattern)
+		if errGlob != nil {
+			err = fmt.Errorf("RouteInputFromFS pattern %s: %s", entry.Pattern, errGlob.Error())
+			return
+		}
+
+		// Pattern without router file is a typo, otherwise routes are silently not validated
+		if len(listName) == 0 {
+			err = fmt.Errorf("RouteInputFromFS pattern %s: router file is not found", entry.Pattern)
+			return
+		}
+
+		for _, name := range listName {
+			listEntryRoute, errMapping := mappingRouteFS(input.fsys, name, methodCheck, entry.Prefix)
+			if errMapping != nil {
+				err = errMapping
+				return
+			}
+
+			for _, route := range listEntryRoute {
+				if isExist[route.Path] {
+					continue
+				}
+
+				isExist[route.Path] = true
+				listRoute = append(listRoute, route)
+			}
+		}
+	}
+
+	return
+}
+
+// routeAppInput - routes registered on fiber app
+type routeAppInput struct {
+	app *fiber.App
+}
+
+// RouteInputFromApp - routes registered on fiber app, including group prefixes and mounted sub apps.
+// Source of the router is not needed at runtime, call MappingRoute after every route is registered.
+//
+// Fiber merges routes of mounted sub apps on startup, so app with mounted sub app must be started first,
+// ex: app.Listen, or app.Handler() which is also executed by app.Test. Otherwise ErrFiberAppNotStarted is returned.
+func RouteInputFromApp(app *fiber.App) RouteInput {
+	return routeAppInput{app: app}
+}
+
+func (input routeAppInput) listRoute(methodCheck method) (listRoute []MappingRoute, err error) {
+	if input.app == nil {
+		err = ErrFiberAppEmpty
+		return
+	}
+
+	// Mounted sub app is registered as middleware without handler until it is merged.
+	// App is not started here, so OnListen hooks are not executed before app.Listen.
+	for _, route := range input.app.GetRoutes() {
+		if len(route.Handlers) == 0 {
+			err = fmt.Errorf("%w, sub app is mounted on %s", ErrFiberAppNotStarted, route.Path)
+			return
+		}
+	}
+
+	isExist := make(map[string]bool)
+	for _, route := range input.app.GetRoutes(true) {
+		if route.Method != methodCheck.String() || isExist[route.Path] {
+			continue
+		}
+
+		isExist[route.Path] = true
+		listRoute = append(listRoute, MappingRoute{
+			Method: route.Method,
+			Path:   route.Path,
+		})
+	}
+
+	return
+}
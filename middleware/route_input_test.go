@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+func TestRouteInputFromApp(t *testing.T) {
+	handler := func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	newApp := func() *fiber.App {
+		app := fiber.New()
+		app.Use(handler)
+
+		api := app.Group("/api/v1")
+		api.Delete("/cities/:id", handler)
+		api.Get("/cities/:id", handler)
+		api.Route("/countries", func(router fiber.Router) {
+			router.Delete("/:id", handler, handler)
+		})
+
+		subApp := fiber.New()
+		subApp.Delete("/airports/:id", handler)
+		api.Mount("/master", subApp)
+		return app
+	}
+
+	// Routes of mounted sub app are merged on startup
+	newStartedApp := func() *fiber.App {
+		app := newApp()
+		app.Handler()
+		return app
+	}
+
+	t.Run("routes of group, route and mounted sub app", func(t *testing.T) {
+		gotListRoute, err := RouteInputFromApp(newStartedApp()).listRoute(DeleteMethod)
+		utils.AssertEqual(t, nil, err, "listRoute")
+
+		wantListRoute := []MappingRoute{
+			{Method: DeleteMethod.String(), Path: "/api/v1/cities/:id"},
+			{Method: DeleteMethod.String(), Path: "/api/v1/countries/:id"},
+			{Method: DeleteMethod.String(), Path: "/api/v1/master/airports/:id"},
+		}
+		if !reflect.DeepEqual(gotListRoute, wantListRoute) {
+			t.Errorf("listRoute() = %v, want %v", gotListRoute, wantListRoute)
+		}
+	})
+
+	t.Run("validated with router source", func(t *testing.T) {
+		routerSource := RouterSource{
+			"^/api/v1/cities/([^/]+)$":          SourceRelation{Source: "city"},
+			"^/api/v1/countries/([^/]+)$":       SourceRelation{Source: "country"},
+			"^/api/v1/master/airports/([^/]+)$": SourceRelation{Source: "airport"},
+		}
+
+		report, err := checkValidRoute(DeleteMethod, routerSource, RouteInputFromApp(newStartedApp()), CoverageConfig{})
+		utils.AssertEqual(t, nil, err, "checkValidRoute")
+		utils.AssertEqual(t, false, report.HasErrors(), "report")
+	})
+
+	t.Run("mounted sub app, app is not started, error", func(t *testing.T) {
+		isListened := false
+		app := newApp()
+		app.Hooks().OnListen(func() error {
+			isListened = true
+			return nil
+		})
+
+		_, err := RouteInputFromApp(app).listRoute(DeleteMethod)
+		utils.AssertEqual(t, true, errors.Is(err, ErrFiberAppNotStarted), "listRoute")
+		utils.AssertEqual(t, false, isListened, "OnListen hook")
+	})
+
+	t.Run("nil app, error", func(t *testing.T) {
+		_, err := RouteInputFromApp(nil).listRoute(DeleteMethod)
+		utils.AssertEqual(t, true, errors.Is(err, ErrFiberAppEmpty), "listRoute")
+	})
+}
+
+func TestRouteInputFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"router/master/city.go": {Data: []byte(`package master
+
+import "github.com/gofiber/fiber/v2"
+
+func City(app fiber.Router) {
+	app.Delete("/cities/:id", controller.DeleteCity)
+}
+`)},
+		"router/master/country.go": {Data: []byte(`package master
+
+import "github.com/gofiber/fiber/v2"
+
+func Country(app fiber.Router) {
+	app.Delete("/countries/:id", controller.DeleteCountry)
+	app.Delete("/cities/:id", controller.DeleteCity)
+}
+`)},
+		"router/airport.json": {Data: []byte(`[{"method": "DELETE", "path": "/airports/:id"}]`)},
+		"router/openapi.yaml": {Data: []byte(`
+openapi: 3.0.3
+paths:
+  /corporates/{id}:
+    delete:
+      operationId: deleteCorporate
+`)},
+	}
+
+	t.Run("every entry with its own prefix, routes are merged", func(t *testing.T) {
+		gotListRoute, err := RouteInputFromFS(fsys,
+			RouteFileEntry{Pattern: "router/master", Prefix: "/api/v1/master"},
+			RouteFileEntry{Pattern: "router/*.json", Prefix: "/api/v1"},
+			RouteFileEntry{Pattern: "router/*.yaml", Prefix: "/api/v2"},
+		).listRoute(DeleteMethod)
+		utils.AssertEqual(t, nil, err, "listRoute")
+
+		wantListRoute := []MappingRoute{
+			{Method: DeleteMethod.String(), Path: "/api/v1/master/cities/:id"},
+			{Method: DeleteMethod.String(), Path: "/api/v1/master/countries/:id"},
+			{Method: DeleteMethod.String(), Path: "/api/v1/airports/:id"},
+			{Method: DeleteMethod.String(), Path: "/api/v2/corporates/:id"},
+		}
+		if !reflect.DeepEqual(gotListRoute, wantListRoute) {
+			t.Errorf("listRoute() = %v, want %v", gotListRoute, wantListRoute)
+		}
+	})
+
+	t.Run("glob of go files", func(t *testing.T) {
+		gotListRoute, err := RouteInputFromFS(fsys, RouteFileEntry{Pattern: "router/master/c*.go"}).listRoute(DeleteMethod)
+		utils.AssertEqual(t, nil, err, "listRoute")
+		utils.AssertEqual(t, 2, len(gotListRoute), "listRoute")
+	})
+
+	t.Run("pattern without router file, error", func(t *testing.T) {
+		_, err := RouteInputFromFS(fsys, RouteFileEntry{Pattern: "router/*.txt"}).listRoute(DeleteMethod)
+		utils.AssertEqual(t, true, err != nil, "listRoute")
+	})
+
+	t.Run("nil fs, error", func(t *testing.T) {
+		_, err := RouteInputFromFS(nil).listRoute(DeleteMethod)
+		utils.AssertEqual(t, true, errors.Is(err, ErrRouteFSEmpty), "listRoute")
+	})
+}
@@ -0,0 +1,566 @@
+package middleware
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// goSource - Go source file of router package, routes are only collected from entry files
+type goSource struct {
+	name    string
+	src     []byte
+	isEntry bool
+}
+
+// routerMethods - fiber.Router methods which register a route, by method name
+var routerMethods = map[string]string{
+	"Get":     GetMethod.String(),
+	"Head":    "HEAD",
+	"Post":    PostMethod.String(),
+	"Put":     PutMethod.String(),
+	"Delete":  DeleteMethod.String(),
+	"Connect": "CONNECT",
+	"Options": "OPTIONS",
+	"Trace":   "TRACE",
+	"Patch":   "PATCH",
+}
+
+// fiberMethods - fiber.Method* constants used by fiber.Router.Add
+var fiberMethods = map[string]string{
+	"MethodGet":     GetMethod.String(),
+	"MethodHead":    "HEAD",
+	"MethodPost":    PostMethod.String(),
+	"MethodPut":     PutMethod.String(),
+	"MethodDelete":  DeleteMethod.String(),
+	"MethodConnect": "CONNECT",
+	"MethodOptions": "OPTIONS",
+	"MethodTrace":   "TRACE",
+	"MethodPatch":   "PATCH",
+}
+
+// fiberImportPath - import path of fiber, every major version
+const fiberImportPath = "github.com/gofiber/fiber"
+
+// maxRouterCallDepth - maximum nested router function calls followed by the parser, stop recursive functions
+const maxRouterCallDepth = 10
+
+// readGoPackage - read every Go file of router package, test files are skipped.
+// If name is a file, only the file is an entry, other files are used to resolve constants and functions.
+func readGoPackage(fsys fs.FS, name string) (listSource []goSource, err error) {
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		err = fmt.Errorf("readGoPackage open router file: %s", err.Error())
+		return
+	}
+
+	dir, entry := name, ""
+	if !info.IsDir() {
+		dir, entry = path.Dir(name), path.Clean(name)
+	}
+
+	listPath, err := fs.Glob(fsys, path.Join(dir, "*"+Golang.String()))
+	if err != nil {
+		return
+	}
+
+	for _, filePath := range listPath {
+		if strings.HasSuffix(filePath, "_test"+Golang.String()) {
+			continue
+		}
+
+		src, errRead := fs.ReadFile(fsys, filePath)
+		if errRead != nil {
+			err = fmt.Errorf("readGoPackage open router file: %s", errRead.Error())
+			return
+		}
+
+		listSource = append(listSource, goSource{
+			name:    filePath,
+			src:     src,
+			isEntry: entry == "" || filePath == entry,
+		})
+	}
+
+	return
+}
+
+// mappingRouteGoSource - parse routes of Go router package by go/ast.
+// Prefix of Group and Route is followed, including router passed to other functions of the package,
+// and constant path is resolved.
+// Format:
+//
+//	api := app.Group("/api/v1/master")
+//	api.Delete(cityPath+"/:id", controller.DeleteCity)
+//	api.Route("/countries", func(router fiber.Router) {
+//		router.Delete("/:id", controller.DeleteCountry)
+//	})
+func mappingRouteGoSource(listSource []goSource, methodCheck method) (listRoute []MappingRoute, err error) {
+	p := &routerParser{
+		fset:         token.NewFileSet(),
+		constants:    make(map[string]ast.Expr),
+		functions:    make(map[string]*ast.FuncDecl),
+		files:        make(map[string]*ast.File),
+		imports:      make(map[*ast.File]map[string]bool),
+		fiberImports: make(map[*ast.File]map[string]bool),
+		routers:      make(map[string]bool),
+		routerFields: make(map[string]bool),
+	}
+
+	listFile := []*ast.File{}
+	listEntry := []*ast.File{}
+	for _, source := range listSource {
+		file, errParse := parser.ParseFile(p.fset, source.name, source.src, 0)
+		if errParse != nil {
+			// Only router file must be valid, other file of the package is only used to resolve constants and functions
+			if !source.isEntry {
+				log.Println("WARNING mappingRouteGoSource: go file is skipped, cannot be parsed:", errParse.Error())
+				continue
+			}
+
+			err = fmt.Errorf("mappingRouteGoSource cannot parse go file: %s", errParse.Error())
+			return
+		}
+
+		listFile = append(listFile, file)
+		if source.isEntry {
+			listEntry = append(listEntry, file)
+		}
+	}
+
+	for _, file := range listFile {
+		p.collectDeclarations(file)
+	}
+
+	// Function called by another function of the package is parsed on its call, with the router prefix
+	isCalled := make(map[string]bool)
+	for _, file := range listFile {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				if ident, ok := call.Fun.(*ast.Ident); ok && p.functions[ident.Name] != nil {
+					isCalled[ident.Name] = true
+				}
+			}
+			return true
+		})
+	}
+
+	for _, file := range listEntry {
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok || funcDecl.Body == nil || (funcDecl.Recv == nil && isCalled[funcDecl.Name.Name]) {
+				continue
+			}
+
+			p.walk(file, funcDecl.Body, p.paramScope(file, funcDecl), 0)
+		}
+	}
+
+	for _, route := range p.routes {
+		if route.Method == methodCheck.String() {
+			listRoute = append(listRoute, route)
+		}
+	}
+
+	return
+}
+
+// routerParser - state of parsing routes of a Go package.
+// Router is only *fiber.App, *fiber.Group, fiber.Router, fiber.New() or Group and Route of a router,
+// so other receivers with the same method name are not routes, ex: cache.Delete(key).
+type routerParser struct {
+	fset         *token.FileSet
+	constants    map[string]ast.Expr           // package level constant and variable
+	functions    map[string]*ast.FuncDecl      // package level function
+	files        map[string]*ast.File          // file which declares the package level function
+	imports      map[*ast.File]map[string]bool // imported package names of each file
+	fiberImports map[*ast.File]map[string]bool // package names of fiber import of each file
+	routers      map[string]bool               // package level router variable
+	routerFields map[string]bool               // router field of package struct, ex: app of s.app
+	routes       []MappingRoute
+}
+
+// routerScope - routers and strings declared inside a function
+type routerScope struct {
+	parent  *routerScope
+	routers map[string]string // prefix of router variable
+	values  map[string]ast.Expr
+}
+
+func newRouterScope(parent *routerScope) *routerScope {
+	return &routerScope{
+		parent:  parent,
+		routers: make(map[string]string),
+		values:  make(map[string]ast.Expr),
+	}
+}
+
+func (s *routerScope) router(name string) (prefix string, ok bool) {
+	for scope := s; scope != nil; scope = scope.parent {
+		if prefix, ok = scope.routers[name]; ok {
+			return
+		}
+		if _, isValue := scope.values[name]; isValue {
+			return
+		}
+	}
+	return
+}
+
+func (s *routerScope) value(name string) (expr ast.Expr, ok bool) {
+	for scope := s; scope != nil; scope = scope.parent {
+		if expr, ok = scope.values[name]; ok {
+			return
+		}
+		if _, isRouter := scope.routers[name]; isRouter {
+			return
+		}
+	}
+	return
+}
+
+// collectDeclarations - package level constants, variables, functions and imports of a file
+func (p *routerParser) collectDeclarations(file *ast.File) {
+	imports, fiberImports := make(map[string]bool), make(map[string]bool)
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name := importName(path)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = true
+		if strings.HasPrefix(path, fiberImportPath) {
+			fiberImports[name] = true
+		}
+	}
+	p.imports[file] = imports
+	p.fiberImports[file] = fiberImports
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil {
+				p.functions[decl.Name.Name] = decl
+				p.files[decl.Name.Name] = file
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.ValueSpec:
+					for i, name := range spec.Names {
+						if i < len(spec.Values) {
+							p.constants[name.Name] = spec.Values[i]
+							p.routers[name.Name] = p.isFiberNew(file, spec.Values[i])
+						} else {
+							p.routers[name.Name] = p.isRouterType(file, spec.Type)
+						}
+					}
+				case *ast.TypeSpec:
+					if structType, ok := spec.Type.(*ast.StructType); ok {
+						for _, field := range structType.Fields.List {
+							for _, name := range field.Names {
+								p.routerFields[name.Name] = p.routerFields[name.Name] || p.isRouterType(file, field.Type)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// walk - collect routes of a function body, router variables are tracked in source order
+func (p *routerParser) walk(file *ast.File, body *ast.BlockStmt, scope *routerScope, depth int) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for i, lhs := range n.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok && len(n.Lhs) == len(n.Rhs) {
+					p.declare(file, scope, ident.Name, n.Rhs[i])
+				}
+			}
+		case *ast.ValueSpec:
+			for i, name := range n.Names {
+				if i < len(n.Values) {
+					p.declare(file, scope, name.Name, n.Values[i])
+				} else if p.isRouterType(file, n.Type) {
+					scope.routers[name.Name] = ""
+				}
+			}
+		case *ast.CallExpr:
+			return p.call(file, n, scope, depth)
+		}
+		return true
+	})
+}
+
+// declare - remember a variable of function, as router prefix or as value
+func (p *routerParser) declare(file *ast.File, scope *routerScope, name string, expr ast.Expr) {
+	if prefix, ok := p.routerPrefix(file, expr, scope); ok {
+		scope.routers[name] = prefix
+		return
+	}
+	scope.values[name] = expr
+}
+
+// call - collect route of a call, follow Route and function of the package.
+// Return false if arguments of the call are already walked.
+func (p *routerParser) call(file *ast.File, call *ast.CallExpr, scope *routerScope, depth int) bool {
+	switch fun := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		name := fun.Sel.Name
+
+		if name == "Route" && len(call.Args) >= 2 {
+			prefix, ok := p.routerPrefix(file, call, scope)
+			funcLit, isFuncLit := call.Args[1].(*ast.FuncLit)
+			if !ok || !isFuncLit {
+				return true
+			}
+
+			routeScope := newRouterScope(scope)
+			if params := funcLit.Type.Params.List; len(params) > 0 && len(params[0].Names) > 0 {
+				routeScope.routers[params[0].Names[0].Name] = prefix
+			}
+			p.walk(file, funcLit.Body, routeScope, depth)
+			return false
+		}
+
+		method, isRoute := routerMethods[name]
+		pathIdx := 0
+		if name == "Add" && len(call.Args) >= 3 {
+			method, isRoute = p.method(call.Args[0], file, scope)
+			pathIdx = 1
+		}
+		if !isRoute || len(call.Args) < pathIdx+2 {
+			return true
+		}
+
+		prefix, ok := p.receiverPrefix(file, fun.X, scope)
+		if !ok {
+			return true
+		}
+
+		path, ok := p.stringValue(call.Args[pathIdx], scope, 0)
+		if !ok {
+			log.Printf("WARNING mappingRouteGoSource: path of %s route on %s cannot be resolved", method, p.fset.Position(call.Pos()))
+			return true
+		}
+
+		p.routes = append(p.routes, MappingRoute{Method: method, Path: joinRoutePath(prefix, path)})
+
+	case *ast.Ident:
+		funcDecl := p.functions[fun.Name]
+		if funcDecl == nil || funcDecl.Body == nil || depth >= maxRouterCallDepth {
+			return true
+		}
+
+		// Router argument is passed as router parameter, router parameter of unknown argument has no prefix
+		funcScope := p.paramScope(p.files[fun.Name], funcDecl)
+		idx := 0
+		for _, field := range funcDecl.Type.Params.List {
+			for _, name := range field.Names {
+				if idx < len(call.Args) {
+					if prefix, ok := p.routerPrefix(file, call.Args[idx], scope); ok {
+						funcScope.routers[name.Name] = prefix
+					} else if value, ok := p.stringValue(call.Args[idx], scope, 0); ok {
+						funcScope.values[name.Name] = &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(value)}
+					}
+				}
+				idx++
+			}
+		}
+
+		p.walk(p.files[fun.Name], funcDecl.Body, funcScope, depth+1)
+	}
+
+	return true
+}
+
+// routerPrefix - prefix of router expression, ex: app.Group("/api"), or variable of router
+func (p *routerParser) routerPrefix(file *ast.File, expr ast.Expr, scope *routerScope) (prefix string, ok bool) {
+	switch expr := expr.(type) {
+	case *ast.ParenExpr:
+		return p.routerPrefix(file, expr.X, scope)
+	case *ast.Ident:
+		return scope.router(expr.Name)
+	case *ast.CallExpr:
+		if p.isFiberNew(file, expr) {
+			return "", true
+		}
+
+		fun, isSelector := expr.Fun.(*ast.SelectorExpr)
+		if !isSelector || (fun.Sel.Name != "Group" && fun.Sel.Name != "Route") || len(expr.Args) == 0 {
+			return
+		}
+
+		parentPrefix, isRouter := p.receiverPrefix(file, fun.X, scope)
+		if !isRouter {
+			return
+		}
+
+		path, isString := p.stringValue(expr.Args[0], scope, 0)
+		if !isString {
+			log.Printf("WARNING mappingRouteGoSource: prefix of %s on %s cannot be resolved", fun.Sel.Name, p.fset.Position(expr.Pos()))
+			return
+		}
+
+		return joinRoutePath(parentPrefix, path), true
+	}
+	return
+}
+
+// receiverPrefix - prefix of receiver of router method, package level router variable and router field have no prefix.
+// Unknown variable and imported package are not routers, ex: ctrl.Delete or http.Get.
+func (p *routerParser) receiverPrefix(file *ast.File, expr ast.Expr, scope *routerScope) (prefix string, ok bool) {
+	if prefix, ok = p.routerPrefix(file, expr, scope); ok {
+		return
+	}
+
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		if _, isValue := scope.value(expr.Name); isValue {
+			return
+		}
+		return "", p.routers[expr.Name]
+	case *ast.SelectorExpr:
+		// Router field of struct, ex: s.app
+		if ident, isIdent := expr.X.(*ast.Ident); isIdent && p.imports[file][ident.Name] {
+			return
+		}
+		return "", p.routerFields[expr.Sel.Name]
+	}
+	return
+}
+
+// paramScope - scope of function with router parameters, ex: app *fiber.App or router fiber.Router, without prefix
+func (p *routerParser) paramScope(file *ast.File, funcDecl *ast.FuncDecl) (scope *routerScope) {
+	scope = newRouterScope(nil)
+	for _, field := range funcDecl.Type.Params.List {
+		if !p.isRouterType(file, field.Type) {
+			continue
+		}
+
+		for _, name := range field.Names {
+			scope.routers[name.Name] = ""
+		}
+	}
+	return
+}
+
+// isRouterType - type expression is *fiber.App, *fiber.Group or fiber.Router
+func (p *routerParser) isRouterType(file *ast.File, expr ast.Expr) bool {
+	star, isPointer := expr.(*ast.StarExpr)
+	if isPointer {
+		expr = star.X
+	}
+
+	selector, isSelector := expr.(*ast.SelectorExpr)
+	if !isSelector || !p.isFiberPackage(file, selector.X) {
+		return false
+	}
+
+	switch selector.Sel.Name {
+	case "App", "Group":
+		return isPointer
+	case "Router":
+		return !isPointer
+	}
+	return false
+}
+
+// isFiberNew - expression is fiber.New(...)
+func (p *routerParser) isFiberNew(file *ast.File, expr ast.Expr) bool {
+	call, isCall := expr.(*ast.CallExpr)
+	if !isCall {
+		return false
+	}
+
+	fun, isSelector := call.Fun.(*ast.SelectorExpr)
+	return isSelector && fun.Sel.Name == "New" && p.isFiberPackage(file, fun.X)
+}
+
+// isFiberPackage - expression is the imported fiber package of file
+func (p *routerParser) isFiberPackage(file *ast.File, expr ast.Expr) bool {
+	ident, isIdent := expr.(*ast.Ident)
+	return isIdent && p.fiberImports[file][ident.Name]
+}
+
+// stringValue - resolve string literal, constant and concatenation of them
+func (p *routerParser) stringValue(expr ast.Expr, scope *routerScope, depth int) (value string, ok bool) {
+	if depth >= maxRouterCallDepth {
+		return
+	}
+
+	switch expr := expr.(type) {
+	case *ast.BasicLit:
+		if expr.Kind == token.STRING {
+			value, err := strconv.Unquote(expr.Value)
+			return value, err == nil
+		}
+	case *ast.ParenExpr:
+		return p.stringValue(expr.X, scope, depth+1)
+	case *ast.BinaryExpr:
+		if expr.Op != token.ADD {
+			return
+		}
+		left, okLeft := p.stringValue(expr.X, scope, depth+1)
+		right, okRight := p.stringValue(expr.Y, scope, depth+1)
+		return left + right, okLeft && okRight
+	case *ast.Ident:
+		if scope != nil {
+			if valueExpr, isValue := scope.value(expr.Name); isValue {
+				return p.stringValue(valueExpr, scope, depth+1)
+			}
+		}
+		if valueExpr, isConstant := p.constants[expr.Name]; isConstant {
+			return p.stringValue(valueExpr, nil, depth+1)
+		}
+	}
+	return
+}
+
+// method - resolve method of fiber.Router.Add, ex: fiber.MethodDelete or "DELETE"
+func (p *routerParser) method(expr ast.Expr, file *ast.File, scope *routerScope) (method string, ok bool) {
+	if selector, isSelector := expr.(*ast.SelectorExpr); isSelector {
+		method, ok = fiberMethods[selector.Sel.Name]
+		return
+	}
+
+	method, ok = p.stringValue(expr, scope, 0)
+	return strings.ToUpper(method), ok
+}
+
+// importName - default package name of import path, ex: github.com/gofiber/fiber/v2 is fiber, gopkg.in/yaml.v3 is yaml
+func importName(path string) (name string) {
+	listElem := strings.Split(path, "/")
+	name = listElem[len(listElem)-1]
+
+	if isMajorVersion := len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == ""; isMajorVersion && len(listElem) > 1 {
+		name = listElem[len(listElem)-2]
+	}
+	if i := strings.Index(name, ".v"); i > 0 {
+		name = name[:i]
+	}
+	return strings.TrimPrefix(name, "go-")
+}
+
+// joinRoutePath - join prefix and path like fiber Group
+func joinRoutePath(prefix, path string) string {
+	if len(path) == 0 || path == "/" {
+		if prefix == "" {
+			return "/"
+		}
+		return prefix
+	}
+
+	if path[0] != '/' {
+		path = "/" + path
+	}
+	return strings.TrimRight(prefix, "/") + path
+}
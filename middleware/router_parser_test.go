@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+func Test_mappingRouteGoSource(t *testing.T) {
+	routerFile := `package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"example.com/app/controller"
+)
+
+const masterPath = "/master"
+
+const cityPath = masterPath + "/cities"
+
+func Handle(app *fiber.App) {
+	api := app.Group("/api/v1")
+
+	api.Delete(
+		cityPath+"/:id",
+		controller.Auth,
+		controller.DeleteCity,
+	)
+	api.Group(masterPath).Delete("/airports/:id", controller.DeleteAirport)
+	api.Get(cityPath+"/:id", controller.GetCity)
+
+	api.Route("/countries", func(router fiber.Router) {
+		router.Delete("/:id", controller.DeleteCountry)
+		router.Add(fiber.MethodDelete, "/:id/translations", controller.DeleteCountryTranslation)
+	})
+
+	corporate(api.Group("/corporates"))
+
+	fiber.Get("/not-a-route", controller.GetCity)
+}
+`
+	corporateFile := `package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"example.com/app/controller"
+)
+
+func corporate(router fiber.Router) {
+	router.Delete("/:id", controller.DeleteCorporate)
+}
+`
+	testFile := `package router
+
+func TestHandle(app *fiber.App) {
+	app.Delete("/test/:id", controller.DeleteTest)
+}
+`
+
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"router.go":      routerFile,
+		"corporate.go":   corporateFile,
+		"router_test.go": testFile,
+	} {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644)
+		utils.AssertEqual(t, nil, err, "write file")
+	}
+
+	t.Run("directory, routes of every file with full prefix", func(t *testing.T) {
+		gotListRoute, err := mappingRoute(DeleteMethod, dir, "")
+		utils.AssertEqual(t, nil, err, "mappingRoute")
+
+		wantListRoute := []MappingRoute{
+			{Method: DeleteMethod.String(), Path: "/api/v1/master/cities/:id"},
+			{Method: DeleteMethod.String(), Path: "/api/v1/master/airports/:id"},
+			{Method: DeleteMethod.String(), Path: "/api/v1/countries/:id"},
+			{Method: DeleteMethod.String(), Path: "/api/v1/countries/:id/translations"},
+			{Method: DeleteMethod.String(), Path: "/api/v1/corporates/:id"},
+		}
+		if !reflect.DeepEqual(gotListRoute, wantListRoute) {
+			t.Errorf("mappingRoute() = %v, want %v", gotListRoute, wantListRoute)
+		}
+	})
+
+	t.Run("file, only routes of the file, helper function of other file is followed", func(t *testing.T) {
+		gotListRoute, err := mappingRoute(GetMethod, filepath.Join(dir, "router.go"), "/app")
+		utils.AssertEqual(t, nil, err, "mappingRoute")
+
+		wantListRoute := []MappingRoute{
+			{Method: GetMethod.String(), Path: "/app/api/v1/master/cities/:id"},
+		}
+		if !reflect.DeepEqual(gotListRoute, wantListRoute) {
+			t.Errorf("mappingRoute() = %v, want %v", gotListRoute, wantListRoute)
+		}
+	})
+
+	t.Run("invalid go file, error", func(t *testing.T) {
+		invalidDir := t.TempDir()
+		err := os.WriteFile(filepath.Join(invalidDir, "router.go"), []byte(`package router func`), 0644)
+		utils.AssertEqual(t, nil, err, "write file")
+
+		_, err = mappingRoute(DeleteMethod, invalidDir, "")
+		utils.AssertEqual(t, true, err != nil, "mappingRoute")
+	})
+
+	t.Run("file, invalid go file of other file is skipped", func(t *testing.T) {
+		invalidDir := t.TempDir()
+		err := os.WriteFile(filepath.Join(invalidDir, "router.go"), []byte(routerFile), 0644)
+		utils.AssertEqual(t, nil, err, "write file")
+		err = os.WriteFile(filepath.Join(invalidDir, "draft.go"), []byte(`package router func`), 0644)
+		utils.AssertEqual(t, nil, err, "write file")
+
+		gotListRoute, err := mappingRoute(GetMethod, filepath.Join(invalidDir, "router.go"), "")
+		utils.AssertEqual(t, nil, err, "mappingRoute")
+
+		wantListRoute := []MappingRoute{
+			{Method: GetMethod.String(), Path: "/api/v1/master/cities/:id"},
+		}
+		if !reflect.DeepEqual(gotListRoute, wantListRoute) {
+			t.Errorf("mappingRoute() = %v, want %v", gotListRoute, wantListRoute)
+		}
+	})
+}
+
+func Test_mappingRouteGoSource_receiver(t *testing.T) {
+	routerFile := `package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+	web "github.com/gofiber/fiber/v2"
+
+	"example.com/app/cache"
+	"example.com/app/controller"
+)
+
+var App = fiber.New()
+
+type server struct {
+	app   *fiber.App
+	cache *cache.Cache
+}
+
+func (s *server) Handle() {
+	s.app.Delete("/servers/:id", controller.DeleteServer)
+	s.cache.Delete("/server-cache/:id", "key")
+}
+
+func Handle(ctrl *controller.Controller, store *cache.Cache) {
+	app := web.New()
+	app.Delete("/cities/:id", ctrl.DeleteCity)
+	App.Delete("/airports/:id", ctrl.DeleteAirport)
+
+	var router fiber.Router
+	router.Delete("/countries/:id", ctrl.DeleteCountry)
+
+	ctrl.Delete("/controller/:id", "key")
+	store.Delete("/store/:id", "key")
+	unknown.Delete("/unknown/:id", "key")
+}
+`
+	gotListRoute, err := mappingRouteGoSource([]goSource{{name: "router.go", src: []byte(routerFile), isEntry: true}}, DeleteMethod)
+	utils.AssertEqual(t, nil, err, "mappingRouteGoSource")
+
+	wantListRoute := []MappingRoute{
+		{Method: DeleteMethod.String(), Path: "/servers/:id"},
+		{Method: DeleteMethod.String(), Path: "/cities/:id"},
+		{Method: DeleteMethod.String(), Path: "/airports/:id"},
+		{Method: DeleteMethod.String(), Path: "/countries/:id"},
+	}
+	if !reflect.DeepEqual(gotListRoute, wantListRoute) {
+		t.Errorf("mappingRouteGoSource() = %v, want %v", gotListRoute, wantListRoute)
+	}
+}
+
+func Test_joinRoutePath(t *testing.T) {
+	tests := []struct {
+		prefix string
+		path   string
+		want   string
+	}{
+		{prefix: "", path: "", want: "/"},
+		{prefix: "/api", path: "/", want: "/api"},
+		{prefix: "/api/", path: "cities", want: "/api/cities"},
+		{prefix: "/api", path: "/cities/:id", want: "/api/cities/:id"},
+	}
+	for _, tt := range tests {
+		utils.AssertEqual(t, tt.want, joinRoutePath(tt.prefix, tt.path), tt.prefix+" "+tt.path)
+	}
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TenantResolver - resolve tenant of HTTP request, ex: agent id from JWT claims.
+// Empty tenant falls back to tenant of request context, then Environment.AgentID.
+type TenantResolver func(c *fiber.Ctx) string
+
+type tenantContextKey struct{}
+
+// WithTenant - set tenant of a check which is not running on HTTP request, ex: CanDelete, DeleteProtected or Plugin
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenantID)
+}
+
+// tenantFromContext - tenant which is set by WithTenant
+func tenantFromContext(ctx context.Context) (tenantID string) {
+	if ctx == nil {
+		return
+	}
+
+	tenantID, _ = ctx.Value(tenantContextKey{}).(string)
+	return
+}
+
+// SetTenancy - enable tenancy mode, only references of the requesting tenant block deletion.
+// Dependent table without tenantColumn in its gorm schema keeps global semantics.
+// Tenant is resolved by resolver, then request context (see WithTenant), then Environment.AgentID.
+// Request without tenant keeps global semantics. Empty tenantColumn disables tenancy mode.
+// Must be called before serving requests.
+func (m *Middleware) SetTenancy(tenantColumn string, resolver ...TenantResolver) *Middleware {
+	m.newSession()
+
+	m.config.tenantColumn = tenantColumn
+	m.config.tenantResolver = nil
+	if len(resolver) > 0 {
+		m.config.tenantResolver = resolver[0]
+	}
+	return m
+}
+
+// requestConfig - config of a HTTP request, with its resolved tenant
+func (m *Middleware) requestConfig(c *fiber.Ctx) (cfg protectionConfig) {
+	cfg = m.config
+	if cfg.tenantColumn == "" {
+		return
+	}
+
+	if cfg.tenantResolver != nil {
+		cfg.tenantID = cfg.tenantResolver(c)
+	}
+	if cfg.tenantID == "" {
+		cfg.tenantID = tenantFromContext(c.UserContext())
+	}
+	if cfg.tenantID == "" {
+		cfg.tenantID = m.env.AgentID
+	}
+	return
+}
+
+// contextConfig - config of a check which is not running on HTTP request, with its resolved tenant
+func (m *Middleware) contextConfig(ctx context.Context) (cfg protectionConfig) {
+	cfg = m.config
+	if cfg.tenantColumn == "" {
+		return
+	}
+
+	cfg.tenantID = tenantFromContext(ctx)
+	if cfg.tenantID == "" {
+		cfg.tenantID = m.env.AgentID
+	}
+	return
+}
+
+// tenantColumnOf - tenant column of table, only if tenancy mode is enabled and table has tenant column
+func (cfg protectionConfig) tenantColumnOf(schemas modelSchemas, tableName string) (tenantColumn string, ok bool) {
+	if cfg.tenantColumn == "" || cfg.tenantID == "" {
+		return
+	}
+
+	if !schemas.hasColumn(tableName, cfg.tenantColumn) {
+		return
+	}
+
+	return cfg.tenantColumn, true
+}
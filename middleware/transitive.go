@@ -0,0 +1,270 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// relationEdge - table and column which are referencing a table, ex: city.country_id
+type relationEdge struct {
+	table  string
+	column string
+}
+
+// dependencyNode - relations referencing a table of relation schema
+type dependencyNode struct {
+	// isSource - table is a source of router source
+	isSource bool
+	// walk - ignored or cascaded relations, referencing rows are walked as part of the deleted chain
+	walk []relationEdge
+	// block - referencing rows outside the deleted chain are blocking the delete
+	block []relationEdge
+}
+
+// dependencyGraph - relation schema keyed by referenced table, ex: country
+type dependencyGraph map[string]dependencyNode
+
+// generateDependencyGraph - graph of the whole relation schema for transitive check.
+// Relations of a source are walked if they are not protected by deleteTables and not nullified,
+// every relation of a table which is not a source is blocking.
+func generateDependencyGraph(db *gorm.DB, listRouterSource RouterSource, deleteTables routerMaps, ignoredTables map[string][]ignoredRelation) (graph dependencyGraph, err error) {
+	graph = make(dependencyGraph)
+
+	listRelationSchema, err := getListRelationSchema(db)
+	if err != nil {
+		return
+	}
+
+	isSource := make(map[string]bool)
+	for _, sourceRelation := range listRouterSource {
+		isSource[sourceRelation.Source] = true
+	}
+
+	isNullified := make(map[string]bool)
+	for source, relations := range ignoredTables {
+		for _, relation := range relations {
+			if relation.action == IgnoreNullify {
+				isNullified[source+"."+relation.table+"."+relation.column] = true
+			}
+		}
+	}
+
+	for _, relationSchema := range listRelationSchema {
+		if relationSchema.TableSource == nil || relationSchema.UsedByTable == nil || relationSchema.UsedByColumn == nil {
+			err = fmt.Errorf("one of relation schema is incomplete")
+			return
+		}
+
+		source := *relationSchema.TableSource
+		edge := relationEdge{table: *relationSchema.UsedByTable, column: *relationSchema.UsedByColumn}
+
+		node := graph[source]
+		node.isSource = isSource[source]
+
+		_, isProtected := deleteTables[source][edge.table]
+		switch {
+		case !node.isSource || isProtected:
+			node.block = append(node.block, edge)
+		case isNullified[source+"."+edge.table+"."+edge.column]:
+			// Nullified rows are kept without reference
+		default:
+			node.walk = append(node.walk, edge)
+		}
+
+		graph[source] = node
+	}
+
+	return
+}
+
+// findChainIds - ids of live rows of edge table which are referencing ids
+func findChainIds(db *gorm.DB, schemas modelSchemas, cfg protectionConfig, edge relationEdge, ids []uuid.UUID) (chainIds []uuid.UUID, err error) {
+	from, where, vars, err := generateReferenceCondition(db, schemas, cfg, edge.table, edge.column, ids)
+	if err == nil && !schemas.hasColumn(edge.table, "id") {
+		err = fmt.Errorf("column id of table %s is not found", edge.table)
+	}
+	if err != nil {
+		err = &ProtectionQueryError{Table: edge.table, Column: edge.column, Err: err}
+		return
+	}
+
+	id := db.Statement.Quote(clause.Column{Table: edge.table, Name: "id"})
+	query := fmt.Sprintf(`SELECT %s AS %s FROM %s WHERE %s`, id, db.Statement.Quote("id"), from, where)
+
+	result := []struct {
+		ID uuid.UUID
+	}{}
+	if errFind := db.Raw(query, vars...).Scan(&result).Error; errFind != nil {
+		err = &ProtectionQueryError{Table: edge.table, Column: edge.column, Err: errFind}
+		return
+	}
+
+	for _, row := range result {
+		chainIds = append(chainIds, row.ID)
+	}
+	return
+}
+
+// countChainViolation - count live rows of edge table which are referencing ids, except rows of the deleted chain
+func countChainViolation(db *gorm.DB, schemas modelSchemas, cfg protectionConfig, edge relationEdge, ids, chainIds []uuid.UUID) (violation Violation, err error) {
+	violation = Violation{UsedByTable: edge.table, UsedByColumn: edge.column}
+
+	from, where, vars, err := generateReferenceCondition(db, schemas, cfg, edge.table, edge.column, ids)
+	if err == nil && len(chainIds) > 0 && !schemas.hasColumn(edge.table, "id") {
+		err = fmt.Errorf("column id of table %s is not found", edge.table)
+	}
+	if err != nil {
+		err = &ProtectionQueryError{Table: edge.table, Column: edge.column, Err: err}
+		return
+	}
+
+	// Self reference, ex: parent_corporate_id, is not blocking if the child is deleted too
+	id := db.Statement.Quote(clause.Column{Table: edge.table, Name: "id"})
+	if len(chainIds) > 0 {
+		where += fmt.Sprintf(` AND %s NOT IN ?`, id)
+		vars = append(vars, chainIds)
+	}
+
+	query := fmt.Sprintf(`SELECT COUNT(1) AS %s FROM %s WHERE %s`, db.Statement.Quote("total"), from, where)
+	if errCount := db.Raw(query, vars...).Scan(&violation.Total).Error; errCount != nil {
+		err = &ProtectionQueryError{Table: edge.table, Column: edge.column, Err: errCount}
+		return
+	}
+
+	if violation.Total == 0 || cfg.violationSampleSize <= 0 {
+		return
+	}
+
+	query = fmt.Sprintf(`SELECT %s AS %s FROM %s WHERE %s ORDER BY %s LIMIT ?`, id, db.Statement.Quote("id"), from, where, id)
+	result := []struct {
+		ID uuid.UUID
+	}{}
+	if errSample := db.Raw(query, append(vars, cfg.violationSampleSize)...).Scan(&result).Error; errSample != nil {
+		err = &ProtectionQueryError{Table: edge.table, Column: edge.column, Err: errSample}
+		return
+	}
+
+	for _, row := range result {
+		violation.ReferenceIDs = append(violation.ReferenceIDs, row.ID)
+	}
+	return
+}
+
+// collectTransitiveViolations - walk ignored and cascaded relations from ids of source table,
+// then count live rows outside the walked chain which are still referencing the chain.
+// Walked rows are remembered per table, so cyclic relations (ex: parent_corporate_id) are walked once.
+func collectTransitiveViolations(db *gorm.DB, graph dependencyGraph, schemas modelSchemas, source string, ids []uuid.UUID, cfg protectionConfig) (violations []Violation, err error) {
+	type chainNode struct {
+		table string
+		ids   []uuid.UUID
+	}
+
+	chain := map[string]map[uuid.UUID]bool{source: {}}
+	for _, id := range ids {
+		chain[source][id] = true
+	}
+
+	queue := []chainNode{{table: source, ids: ids}}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		for _, edge := range graph[node.table].walk {
+			chainIds, errFind := findChainIds(db, schemas, cfg, edge, node.ids)
+			if errFind != nil {
+				err = errFind
+				return
+			}
+
+			if chain[edge.table] == nil {
+				chain[edge.table] = make(map[uuid.UUID]bool)
+			}
+
+			newIds := []uuid.UUID{}
+			for _, id := range chainIds {
+				if !chain[edge.table][id] {
+					chain[edge.table][id] = true
+					newIds = append(newIds, id)
+				}
+			}
+
+			if len(newIds) > 0 {
+				queue = append(queue, chainNode{table: edge.table, ids: newIds})
+			}
+		}
+	}
+
+	// Sort tables, so violations are deterministic
+	listTable := make([]string, 0, len(chain))
+	for table := range chain {
+		listTable = append(listTable, table)
+	}
+	sort.Strings(listTable)
+
+	for _, table := range listTable {
+		// Blocking relations of deleted ids are already checked by router map
+		chainIds := chain[table]
+		if table == source {
+			chainIds = make(map[uuid.UUID]bool)
+			for id := range chain[source] {
+				chainIds[id] = true
+			}
+			for _, id := range ids {
+				delete(chainIds, id)
+			}
+		}
+		if len(chainIds) == 0 {
+			continue
+		}
+
+		for _, edge := range graph[table].block {
+			violation, errCount := countChainViolation(db, schemas, cfg, edge, toIds(chainIds), toIds(chain[edge.table]))
+			if errCount != nil {
+				err = errCount
+				return
+			}
+
+			if violation.Total > 0 {
+				violation.ReferencedTable = table
+				violations = append(violations, violation)
+			}
+		}
+	}
+
+	return
+}
+
+// validateTransitiveQuery - data is allowed to be deleted if no row outside the walked chain is referencing the chain.
+// If transitive query is failed, the result follows failure policy.
+func validateTransitiveQuery(db *gorm.DB, graph dependencyGraph, schemas modelSchemas, source string, ids []uuid.UUID, cfg protectionConfig) (isAllowed bool, violations []Violation, err error) {
+	violations, err = collectTransitiveViolations(db, graph, schemas, source, ids, cfg)
+	if err != nil {
+		log.Println("ERROR validateTransitiveQuery:", err.Error())
+
+		if cfg.failurePolicy == FailOpen {
+			log.Println("WARNING validateTransitiveQuery: fail open, data is allowed to be deleted without transitive protection")
+			isAllowed, violations, err = true, nil, nil
+		}
+		return
+	}
+
+	isAllowed = len(violations) == 0
+	return
+}
+
+// toIds - sorted ids of a set
+func toIds(set map[uuid.UUID]bool) (ids []uuid.UUID) {
+	ids = make([]uuid.UUID, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].String() < ids[j].String()
+	})
+	return
+}
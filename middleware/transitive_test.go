@@ -0,0 +1,238 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/google/uuid"
+	"github.com/terra-discover/bbcrs-helper-lib/pkg/lib"
+	standardModel "github.com/terra-discover/bbcrs-migration-lib/model"
+)
+
+func TestTransitive(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	listRelationSchema := []standardModel.RelationSchema{
+		{
+			ColumnSource: lib.Strptr("id"),
+			TableSource:  lib.Strptr("country"),
+			UsedByColumn: lib.Strptr("country_id"),
+			UsedByTable:  lib.Strptr("city"),
+		},
+		{
+			ColumnSource: lib.Strptr("id"),
+			TableSource:  lib.Strptr("city"),
+			UsedByColumn: lib.Strptr("city_id"),
+			UsedByTable:  lib.Strptr("airport"),
+		},
+		{
+			ColumnSource: lib.Strptr("id"),
+			TableSource:  lib.Strptr("corporate"),
+			UsedByColumn: lib.Strptr("parent_corporate_id"),
+			UsedByTable:  lib.Strptr("corporate"),
+		},
+		{
+			ColumnSource: lib.Strptr("id"),
+			TableSource:  lib.Strptr("corporate"),
+			UsedByColumn: lib.Strptr("corporate_id"),
+			UsedByTable:  lib.Strptr("agent_corporate"),
+		},
+	}
+	for i := range listRelationSchema {
+		mod := db.Create(&listRelationSchema[i])
+		utils.AssertEqual(t, nil, mod.Error)
+	}
+
+	m := NewMiddleware(Environment{}, db)
+	m.ReplaceRouterSource(RouterSource{
+		UseMasterPattern("countries"): SourceRelation{
+			Source:         "country",
+			IgnoreRelation: []string{"city"},
+		},
+		UseMasterPattern("corporates"): SourceRelation{
+			Source:         "corporate",
+			IgnoreRelation: []string{"corporate"},
+		},
+	}).SetModelMigrations(testSupport__GetModelMigrations())
+	utils.AssertEqual(t, nil, m.Error, "validate err")
+
+	// Mock data, country -> city -> airport
+	countryID := uuid.New()
+	country := standardModel.Country{}
+	country.ID = &countryID
+	country.CountryCode = lib.Strptr(lib.RandomChars(3))
+	country.CountryName = lib.Strptr(lib.RandomChars(6))
+	err := db.Create(&country).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	cityID := uuid.New()
+	city := standardModel.City{}
+	city.ID = &cityID
+	city.CityCode = lib.Strptr(lib.RandomChars(6))
+	city.CityName = lib.Strptr(lib.RandomChars(6))
+	city.CountryID = &countryID
+	err = db.Create(&city).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	airport := standardModel.Airport{
+		AirportAPI: standardModel.AirportAPI{
+			AirportCode: lib.Strptr(lib.RandomChars(6)),
+			AirportName: lib.Strptr(lib.RandomChars(6)),
+			CityID:      &cityID,
+		},
+	}
+	err = db.Create(&airport).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	// Case 1: one level, ignored city is not checked
+	decision, err := m.CanDelete(context.Background(), "country", []uuid.UUID{countryID})
+	utils.AssertEqual(t, nil, err, "validate err")
+	utils.AssertEqual(t, true, decision.IsAllowed, "validate one level")
+
+	// Case 2: transitive, airport is referencing city of the country
+	m.SetTransitive(true)
+	var errViolation *ProtectionViolationError
+	decision, err = m.CanDelete(context.Background(), "country", []uuid.UUID{countryID})
+	utils.AssertEqual(t, true, errors.As(err, &errViolation), "validate err")
+	utils.AssertEqual(t, "country", errViolation.Table, "validate violation source")
+	utils.AssertEqual(t, true, decision.IsProtected, "validate protected")
+	utils.AssertEqual(t, false, decision.IsAllowed, "validate transitive")
+	utils.AssertEqual(t, 1, len(decision.Violations), "validate violations")
+	utils.AssertEqual(t, "airport", decision.Violations[0].UsedByTable, "validate violation table")
+	utils.AssertEqual(t, "city_id", decision.Violations[0].UsedByColumn, "validate violation column")
+	utils.AssertEqual(t, "city", decision.Violations[0].ReferencedTable, "validate violation referenced table")
+	utils.AssertEqual(t, int64(1), decision.Violations[0].Total, "validate violation total")
+
+	// Case 3: transitive, deleted airport is not referencing the chain
+	err = db.Delete(&airport).Error
+	utils.AssertEqual(t, nil, err, "delete airport")
+	decision, err = m.CanDelete(context.Background(), "country", []uuid.UUID{countryID})
+	utils.AssertEqual(t, nil, err, "validate err")
+	utils.AssertEqual(t, true, decision.IsAllowed, "validate transitive without reference")
+
+	// Mock data, corporate cycle A -> B -> A
+	corporateAID, corporateBID := uuid.New(), uuid.New()
+	corporateA := standardModel.Corporate{CorporateName: lib.Strptr("A"), ParentCorporateID: &corporateBID}
+	corporateA.ID = &corporateAID
+	corporateB := standardModel.Corporate{CorporateName: lib.Strptr("B"), ParentCorporateID: &corporateAID}
+	corporateB.ID = &corporateBID
+	err = db.Create(&[]standardModel.Corporate{corporateA, corporateB}).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	// Case 4: transitive, cyclic self reference is walked once
+	decision, err = m.CanDelete(context.Background(), "corporate", []uuid.UUID{corporateAID})
+	utils.AssertEqual(t, nil, err, "validate err")
+	utils.AssertEqual(t, true, decision.IsAllowed, "validate cycle")
+
+	// Case 5: transitive, child corporate in the chain is still referenced
+	agentCorporate := standardModel.AgentCorporate{AgentID: lib.GenUUID(), CorporateID: &corporateBID}
+	err = db.Create(&agentCorporate).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	decision, err = m.CanDelete(context.Background(), "corporate", []uuid.UUID{corporateAID})
+	utils.AssertEqual(t, true, errors.As(err, &errViolation), "validate err")
+	utils.AssertEqual(t, false, decision.IsAllowed, "validate cycle with reference")
+	utils.AssertEqual(t, 1, len(decision.Violations), "validate violations")
+	utils.AssertEqual(t, "agent_corporate", decision.Violations[0].UsedByTable, "validate violation table")
+	utils.AssertEqual(t, "corporate", decision.Violations[0].ReferencedTable, "validate violation referenced table")
+}
+
+func TestTransitive_deactivate(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	listRelationSchema := []standardModel.RelationSchema{
+		{
+			ColumnSource: lib.Strptr("id"),
+			TableSource:  lib.Strptr("corporate"),
+			UsedByColumn: lib.Strptr("parent_corporate_id"),
+			UsedByTable:  lib.Strptr("corporate"),
+		},
+		{
+			ColumnSource: lib.Strptr("id"),
+			TableSource:  lib.Strptr("corporate"),
+			UsedByColumn: lib.Strptr("corporate_id"),
+			UsedByTable:  lib.Strptr("agent_corporate"),
+		},
+	}
+	for i := range listRelationSchema {
+		mod := db.Create(&listRelationSchema[i])
+		utils.AssertEqual(t, nil, mod.Error)
+	}
+
+	m := NewMiddleware(Environment{}, db)
+	m.ReplaceRouterSource(RouterSource{
+		UseMasterPattern("corporates"): SourceRelation{
+			Source:         "corporate",
+			IgnoreRelation: []string{"corporate"},
+			Deactivate: &DeactivateRule{
+				Field:        "is_active",
+				Value:        false,
+				BatchActions: []string{"deactivate"},
+			},
+		},
+	}).SetModelMigrations(testSupport__GetModelMigrations())
+	utils.AssertEqual(t, nil, m.Error, "validate err")
+
+	app := fiber.New()
+	app.Use(m.Protect)
+	app.Put("/api/v1/master/corporates/:id", func(c *fiber.Ctx) error {
+		return c.Status(200).JSON(fiber.Map{"status": 200})
+	})
+	app.Post("/api/v1/master/batch-actions/:action/:module", func(c *fiber.Ctx) error {
+		return c.Status(200).JSON(fiber.Map{"status": 200})
+	})
+
+	// Mock data, parent corporate -> child corporate -> agent corporate
+	parentID, childID := uuid.New(), uuid.New()
+	parent := standardModel.Corporate{CorporateName: lib.Strptr("Parent")}
+	parent.ID = &parentID
+	child := standardModel.Corporate{CorporateName: lib.Strptr("Child"), ParentCorporateID: &parentID}
+	child.ID = &childID
+	err := db.Create(&[]standardModel.Corporate{parent, child}).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	agentCorporate := standardModel.AgentCorporate{AgentID: lib.GenUUID(), CorporateID: &childID}
+	err = db.Create(&agentCorporate).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	jsonHeaders := map[string]string{fiber.HeaderContentType: fiber.MIMEApplicationJSON}
+	path := "/api/v1/master/corporates/" + parentID.String()
+	batchBody := `["` + parentID.String() + `"]`
+
+	// Case 1: one level, ignored child corporate is not checked
+	res, _, err := lib.PutTest(app, path, jsonHeaders, `{"is_active": false}`)
+	utils.AssertEqual(t, nil, err, "Must success")
+	utils.AssertEqual(t, 200, res.StatusCode, "validate one level")
+
+	// Case 2: transitive, agent corporate is referencing child corporate of the parent
+	m.SetTransitive(true)
+	res, body, err := lib.PutTest(app, path, jsonHeaders, `{"is_active": false}`)
+	utils.AssertEqual(t, nil, err, "Must success")
+	utils.AssertEqual(t, 405, res.StatusCode, "validate transitive")
+	violations, _ := body["violations"].([]interface{})
+	utils.AssertEqual(t, 1, len(violations), "validate violations")
+	violation, _ := violations[0].(map[string]interface{})
+	utils.AssertEqual(t, "agent_corporate", violation["used_by_table"], "validate violation table")
+
+	res, _, err = lib.PostTest(app, "/api/v1/master/batch-actions/deactivate/corporates", jsonHeaders, batchBody)
+	utils.AssertEqual(t, nil, err, "Must success")
+	utils.AssertEqual(t, 405, res.StatusCode, "validate transitive batch")
+
+	// Case 3: transitive, deleted agent corporate is not referencing the chain
+	err = db.Delete(&agentCorporate).Error
+	utils.AssertEqual(t, nil, err, "delete agent corporate")
+	res, _, err = lib.PutTest(app, path, jsonHeaders, `{"is_active": false}`)
+	utils.AssertEqual(t, nil, err, "Must success")
+	utils.AssertEqual(t, 200, res.StatusCode, "validate transitive without reference")
+}
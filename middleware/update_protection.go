@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+func isUpdateMethod(method string) bool {
+	return method == "PUT" || method == "PATCH"
+}
+
+// matchingRoute - map route with compiled route, the last matched route is used
+func matchingRoute(routes []compiledRoute, route string) (r *compiledRoute, id *uuid.UUID) {
+	for i := range routes {
+		result := routes[i].regex.FindStringSubmatch(route)
+		if len(result) > 1 {
+			idParam, err := uuid.Parse(result[1])
+			if nil == err && idParam != uuid.Nil {
+				r = &routes[i]
+				id = &idParam
+			}
+		}
+	}
+
+	return
+}
+
+// parseRequestValues - top level values of JSON or url encoded form request body
+func parseRequestValues(c *fiber.Ctx) (requestValues map[string]interface{}, err error) {
+	requestValues = make(map[string]interface{})
+
+	body := c.Body()
+	if len(body) == 0 {
+		return
+	}
+
+	if strings.HasPrefix(strings.ToLower(c.Get(fiber.HeaderContentType)), fiber.MIMEApplicationForm) {
+		c.Request().PostArgs().VisitAll(func(key, value []byte) {
+			requestValues[string(key)] = string(value)
+		})
+		return
+	}
+
+	err = json.Unmarshal(body, &requestValues)
+	return
+}
+
+// findChangedColumns - immutable columns of the stored source row which are changed by request values.
+// Column which is not requested is not changed. Missing row is not changed, so handler can respond not found.
+// Values are compared by data type of the column, see isSameValue.
+func findChangedColumns(db *gorm.DB, schemas modelSchemas, dataTypes map[string]schema.DataType, route compiledRoute, id uuid.UUID, requestValues map[string]interface{}) (changedColumns []string, err error) {
+	requestedColumns := []string{}
+	for _, column := range route.immutableColumns {
+		if _, ok := requestValues[column]; ok {
+			requestedColumns = append(requestedColumns, column)
+		}
+	}
+	if len(requestedColumns) == 0 {
+		return
+	}
+
+	query := db.Table(route.source).
+		Select(requestedColumns).
+		Where(clause.Eq{Column: clause.Column{Table: route.source, Name: "id"}, Value: id})
+	if schemas.hasColumn(route.source, softDeleteColumn) {
+		query = query.Where(clause.Eq{Column: clause.Column{Table: route.source, Name: softDeleteColumn}, Value: nil})
+	}
+
+	storedValues := make(map[string]interface{})
+	if errTake := query.Take(&storedValues).Error; errTake != nil {
+		if errors.Is(errTake, gorm.ErrRecordNotFound) {
+			return
+		}
+
+		err = &ProtectionQueryError{Table: route.source, Column: strings.Join(requestedColumns, ","), Err: errTake}
+		return
+	}
+
+	for _, column := range requestedColumns {
+		if !isSameValue(dataTypes[column], storedValues[column], requestValues[column]) {
+			changedColumns = append(changedColumns, column)
+		}
+	}
+
+	return
+}
+
+// isSameValue - compare stored value with request value by data type of the column,
+// ex: stored int64 1 with JSON true of bool, stored "10.50" with JSON 10.5 of numeric,
+// stored time with RFC3339 string in other zone.
+// Value which cannot be parsed by the data type is compared as string.
+func isSameValue(dataType schema.DataType, storedValue, requestValue interface{}) bool {
+	if storedValue == nil || requestValue == nil {
+		return storedValue == nil && requestValue == nil
+	}
+
+	switch dataType {
+	case schema.Bool:
+		storedBool, errStored := toBool(storedValue)
+		requestBool, errRequest := toBool(requestValue)
+		if errStored == nil && errRequest == nil {
+			return storedBool == requestBool
+		}
+	case schema.Int, schema.Uint, schema.Float:
+		storedNumber, isStored := new(big.Rat).SetString(toComparableString(storedValue))
+		requestNumber, isRequest := new(big.Rat).SetString(toComparableString(requestValue))
+		if isStored && isRequest {
+			return storedNumber.Cmp(requestNumber) == 0
+		}
+	case schema.Time:
+		storedTime, errStored := toTime(storedValue)
+		requestTime, errRequest := toTime(requestValue)
+		if errStored == nil && errRequest == nil {
+			// Databases store at most microsecond
+			return storedTime.Truncate(time.Microsecond).Equal(requestTime.Truncate(time.Microsecond))
+		}
+	}
+
+	return toComparableString(storedValue) == toComparableString(requestValue)
+}
+
+// toBool - bool of stored or request value, ex: int64 1 of sqlite or "true" of form request
+func toBool(value interface{}) (result bool, err error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case int64:
+		return v != 0, nil
+	case float64:
+		return v != 0, nil
+	}
+
+	return strconv.ParseBool(toComparableString(value))
+}
+
+// timeLayouts - layouts of time value which is stored as string or requested
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999-07:00", "2006-01-02 15:04:05.999999999", "2006-01-02"}
+
+// toTime - time of stored or request value
+func toTime(value interface{}) (result time.Time, err error) {
+	if v, ok := value.(time.Time); ok {
+		return v, nil
+	}
+
+	str := toComparableString(value)
+	for _, layout := range timeLayouts {
+		if result, err = time.Parse(layout, str); err == nil {
+			return
+		}
+	}
+
+	return
+}
+
+func toComparableString(value interface{}) string {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	}
+
+	return fmt.Sprint(value)
+}
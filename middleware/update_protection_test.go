@@ -0,0 +1,287 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/google/uuid"
+	"github.com/terra-discover/bbcrs-helper-lib/pkg/lib"
+	standardModel "github.com/terra-discover/bbcrs-migration-lib/model"
+	"gorm.io/gorm/schema"
+)
+
+func TestUpdateProtection(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	relationSchema := standardModel.RelationSchema{
+		ColumnSource: lib.Strptr("id"),
+		TableSource:  lib.Strptr("city"),
+		UsedByColumn: lib.Strptr("city_id"),
+		UsedByTable:  lib.Strptr("airport"),
+	}
+	mod := db.Create(&relationSchema)
+	utils.AssertEqual(t, nil, mod.Error)
+
+	m := NewMiddleware(Environment{}, db)
+	m.ReplaceRouterSource(RouterSource{
+		UseMasterPattern("cities"): SourceRelation{
+			Source:           "city",
+			ImmutableColumns: []string{"city_code"},
+		},
+	}).SetModelMigrations(testSupport__GetModelMigrations())
+	utils.AssertEqual(t, nil, m.Error, "validate err")
+
+	app := fiber.New()
+	app.Use(m.Protect)
+	app.Put("/api/v1/master/cities/:id", func(c *fiber.Ctx) error {
+		return c.Status(200).JSON(fiber.Map{"status": 200})
+	})
+	app.Patch("/api/v1/master/cities/:id", func(c *fiber.Ctx) error {
+		return c.Status(200).JSON(fiber.Map{"status": 200})
+	})
+
+	createCity := func() standardModel.City {
+		cityID := uuid.New()
+		city := standardModel.City{}
+		city.ID = &cityID
+		city.CityCode = lib.Strptr(lib.RandomChars(6))
+		city.CityName = lib.Strptr(lib.RandomChars(6))
+		err := db.Create(&city).Error
+		utils.AssertEqual(t, nil, err, "mock data")
+		return city
+	}
+
+	usedCity := createCity()
+	airport := standardModel.Airport{
+		AirportAPI: standardModel.AirportAPI{
+			AirportCode: lib.Strptr(lib.RandomChars(6)),
+			AirportName: lib.Strptr(lib.RandomChars(6)),
+			CityID:      usedCity.ID,
+		},
+	}
+	err := db.Create(&airport).Error
+	utils.AssertEqual(t, nil, err, "mock data")
+
+	unusedCity := createCity()
+
+	jsonHeaders := map[string]string{fiber.HeaderContentType: fiber.MIMEApplicationJSON}
+	formHeaders := map[string]string{fiber.HeaderContentType: fiber.MIMEApplicationForm}
+
+	tests := []struct {
+		name       string
+		method     string
+		city       standardModel.City
+		headers    map[string]string
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "referenced, immutable column changed, rejected",
+			method:     http.MethodPut,
+			city:       usedCity,
+			headers:    jsonHeaders,
+			body:       `{"city_code": "CHANGED", "city_name": "changed"}`,
+			wantStatus: 405,
+		},
+		{
+			name:       "referenced, immutable column changed by patch form, rejected",
+			method:     http.MethodPatch,
+			city:       usedCity,
+			headers:    formHeaders,
+			body:       "city_code=CHANGED",
+			wantStatus: 405,
+		},
+		{
+			name:       "referenced, immutable column not changed, allowed",
+			method:     http.MethodPut,
+			city:       usedCity,
+			headers:    jsonHeaders,
+			body:       fmt.Sprintf(`{"city_code": "%s", "city_name": "changed"}`, *usedCity.CityCode),
+			wantStatus: 200,
+		},
+		{
+			name:       "referenced, immutable column not requested, allowed",
+			method:     http.MethodPatch,
+			city:       usedCity,
+			headers:    jsonHeaders,
+			body:       `{"city_name": "changed"}`,
+			wantStatus: 200,
+		},
+		{
+			name:       "not referenced, immutable column changed, allowed",
+			method:     http.MethodPut,
+			city:       unusedCity,
+			headers:    jsonHeaders,
+			body:       `{"city_code": "CHANGED"}`,
+			wantStatus: 200,
+		},
+		{
+			name:       "invalid body, bad request",
+			method:     http.MethodPut,
+			city:       usedCity,
+			headers:    jsonHeaders,
+			body:       `{"city_code": `,
+			wantStatus: 400,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/api/v1/master/cities/" + tt.city.ID.String()
+			res, err := app.Test(lib.HTTPRequest(tt.method, path, tt.headers, tt.body))
+			utils.AssertEqual(t, nil, err, "Must success")
+			utils.AssertEqual(t, tt.wantStatus, res.StatusCode, "validate status")
+		})
+	}
+
+	// Message list the changed immutable columns
+	res, body, err := lib.PutTest(app, "/api/v1/master/cities/"+usedCity.ID.String(), jsonHeaders, `{"city_code": "CHANGED"}`)
+	utils.AssertEqual(t, nil, err, "Must success")
+	utils.AssertEqual(t, 405, res.StatusCode, "validate status")
+	message, _ := body["message"].(string)
+	utils.AssertEqual(t, true, strings.Contains(message, "city_code"), "validate message")
+
+	// Changed immutable columns are returned by Check as typed error
+	var errCheck error
+	appCheck := fiber.New()
+	appCheck.Put("/*", func(c *fiber.Ctx) error {
+		_, errCheck = m.Check(c)
+		return nil
+	})
+	_, _, err = lib.PutTest(appCheck, "/api/v1/master/cities/"+usedCity.ID.String(), jsonHeaders, `{"city_code": "CHANGED"}`)
+	utils.AssertEqual(t, nil, err, "Must success")
+
+	var errViolation *ProtectionViolationError
+	utils.AssertEqual(t, true, errors.As(errCheck, &errViolation), "validate ProtectionViolationError")
+	utils.AssertEqual(t, ActionUpdate, errViolation.Action, "validate action")
+	utils.AssertEqual(t, []string{"city_code"}, errViolation.ChangedColumns, "validate changed columns")
+
+	// Invalid body is returned by Check as typed error
+	_, _, err = lib.PutTest(appCheck, "/api/v1/master/cities/"+usedCity.ID.String(), jsonHeaders, `{"city_code": `)
+	utils.AssertEqual(t, nil, err, "Must success")
+
+	var errRequest *RequestError
+	utils.AssertEqual(t, true, errors.As(errCheck, &errRequest), "validate RequestError")
+	utils.AssertEqual(t, fiber.StatusBadRequest, errRequest.StatusCode, "validate status code")
+	utils.AssertEqual(t, true, errRequest.Err != nil, "validate underlying err")
+}
+
+func TestUpdateProtection_failurePolicy(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	relationSchema := standardModel.RelationSchema{
+		ColumnSource: lib.Strptr("id"),
+		TableSource:  lib.Strptr("city"),
+		UsedByColumn: lib.Strptr("city_id"),
+		UsedByTable:  lib.Strptr("airport"),
+	}
+	mod := db.Create(&relationSchema)
+	utils.AssertEqual(t, nil, mod.Error)
+
+	m := NewMiddleware(Environment{}, db)
+	m.ReplaceRouterSource(RouterSource{
+		UseMasterPattern("cities"): SourceRelation{
+			Source:           "city",
+			ImmutableColumns: []string{"city_code"},
+		},
+	}).SetModelMigrations(testSupport__GetModelMigrations())
+	utils.AssertEqual(t, nil, m.Error, "validate err")
+
+	// Stored row cannot be queried
+	err := db.Migrator().DropTable(&standardModel.City{})
+	utils.AssertEqual(t, nil, err, "drop table")
+
+	app := fiber.New()
+	app.Use(m.Protect)
+	app.Put("/api/v1/master/cities/:id", func(c *fiber.Ctx) error {
+		return c.Status(200).JSON(fiber.Map{"status": 200})
+	})
+
+	jsonHeaders := map[string]string{fiber.HeaderContentType: fiber.MIMEApplicationJSON}
+	path := "/api/v1/master/cities/" + uuid.New().String()
+
+	tests := []struct {
+		name       string
+		policy     FailurePolicy
+		wantStatus int
+	}{
+		{
+			name:       "fail closed, unavailable",
+			policy:     FailClosed,
+			wantStatus: 503,
+		},
+		{
+			name:       "fail open, allowed",
+			policy:     FailOpen,
+			wantStatus: 200,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.SetFailurePolicy(tt.policy)
+			utils.AssertEqual(t, nil, m.Error, "validate err")
+
+			res, _, err := lib.PutTest(app, path, jsonHeaders, `{"city_code": "CHANGED"}`)
+			utils.AssertEqual(t, nil, err, "Must success")
+			utils.AssertEqual(t, tt.wantStatus, res.StatusCode, "validate status")
+		})
+	}
+}
+
+func Test_isSameValue(t *testing.T) {
+	now := time.Now()
+	jakarta := time.FixedZone("WIB", 7*60*60)
+
+	tests := []struct {
+		name         string
+		dataType     schema.DataType
+		storedValue  interface{}
+		requestValue interface{}
+		want         bool
+	}{
+		{name: "same string", dataType: schema.String, storedValue: "IDR", requestValue: "IDR", want: true},
+		{name: "different string", dataType: schema.String, storedValue: "IDR", requestValue: "USD", want: false},
+		{name: "stored bytes", dataType: schema.String, storedValue: []byte("IDR"), requestValue: "IDR", want: true},
+		{name: "stored int, json number", dataType: schema.Int, storedValue: int64(10), requestValue: float64(10), want: true},
+		{name: "stored int, different json number", dataType: schema.Int, storedValue: int64(10), requestValue: float64(10.5), want: false},
+		{name: "stored numeric string, json number", dataType: schema.Float, storedValue: "10.50", requestValue: float64(10.5), want: true},
+		{name: "stored numeric bytes, different json number", dataType: schema.Float, storedValue: []byte("10.50"), requestValue: float64(10.51), want: false},
+		{name: "stored numeric string, form value", dataType: schema.Float, storedValue: "10.50", requestValue: "10.5", want: true},
+		{name: "stored int bool, json bool", dataType: schema.Bool, storedValue: int64(1), requestValue: true, want: true},
+		{name: "stored int bool, different json bool", dataType: schema.Bool, storedValue: int64(1), requestValue: false, want: false},
+		{name: "stored bool, form value", dataType: schema.Bool, storedValue: false, requestValue: "false", want: true},
+		{name: "stored time, same time", dataType: schema.Time, storedValue: now, requestValue: now.UTC(), want: true},
+		{name: "stored time, RFC3339 string of other zone", dataType: schema.Time, storedValue: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			requestValue: "2024-01-02T10:04:05+07:00", want: true},
+		{name: "stored time, string of higher precision", dataType: schema.Time, storedValue: time.Date(2024, 1, 2, 3, 4, 5, 123456000, jakarta),
+			requestValue: "2024-01-02T03:04:05.123456789+07:00", want: true},
+		{name: "stored time, different time string", dataType: schema.Time, storedValue: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			requestValue: "2024-01-02T03:04:06Z", want: false},
+		{name: "stored time string, same time", dataType: schema.Time, storedValue: "2024-01-02 03:04:05+00:00",
+			requestValue: "2024-01-02T03:04:05Z", want: true},
+		{name: "unknown data type, compared as string", storedValue: int64(10), requestValue: float64(10), want: true},
+		{name: "both nil", dataType: schema.String, storedValue: nil, requestValue: nil, want: true},
+		{name: "stored nil", dataType: schema.String, storedValue: nil, requestValue: "IDR", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			utils.AssertEqual(t, tt.want, isSameValue(tt.dataType, tt.storedValue, tt.requestValue), "validate same value")
+		})
+	}
+}
@@ -1,10 +1,10 @@
 package middleware
 
 import (
-	"encoding/json"
 	"fmt"
-	"log"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -13,26 +13,25 @@ import (
 	"gorm.io/gorm"
 )
 
-// validateRouterSource - check duplicate route, validate route, and validate table listed on deleteRouterSource
+// validateRouterSource - check duplicate route, validate route, lint patterns, and validate table listed on deleteRouterSource.
+// Every issue is listed on report, err is only returned if routes of input cannot be read.
 // Note: Only can compare with components inside this service
-func validateRouterSource(db *gorm.DB, modelMigrations []interface{}, routerFileDir, routerPrefix string) (err error) {
+func validateRouterSource(db *gorm.DB, routerSource RouterSource, modelMigrations []interface{}, input RouteInput, coverage CoverageConfig) (report ValidationReport, err error) {
 	// Set method to check
 	methodCheck := DeleteMethod
 
-	// Get router source
-	routerSource := getDeleteRouterSource()
-
-	// 1. check valid route
-	err = checkValidRoute(methodCheck, routerSource, routerFileDir, routerPrefix)
+	// 1. check valid route and coverage of route
+	report, err = checkValidRoute(methodCheck, routerSource, input, coverage)
 	if err != nil {
 		return
 	}
 
-	// 2. check valid table
-	err = checkValidTable(db, routerSource, modelMigrations)
-	if err != nil {
-		return
-	}
+	// 2. lint patterns for paths which are not listed on router file
+	report.add(lintRouterSource(routerSource)...)
+
+	// 3. check valid table
+	tableReport := checkValidTable(db, routerSource, modelMigrations)
+	report.add(tableReport.Issues...)
 
 	return
 }
@@ -42,20 +41,24 @@ type MappingRoute struct {
 	Path   string `json:"path"`
 }
 
-func checkValidRoute(methodCheck method, routerSource RouterSource, routerFileDir, routerPrefix string) (err error) {
+func checkValidRoute(methodCheck method, routerSource RouterSource, input RouteInput, coverage CoverageConfig) (report ValidationReport, err error) {
 
-	listRoute, errMapping := mappingRoute(methodCheck, routerFileDir, routerPrefix)
+	listRoute, errMapping := input.listRoute(methodCheck)
 	if errMapping != nil {
 		err = errMapping
 		return
 	}
 
-	errValidate := validateRoute(routerSource, listRoute)
-	if errValidate != nil {
-		err = errValidate
+	report = validateRoute(routerSource, listRoute)
+
+	// Batch action is requested by POST
+	listPostRoute, errMapping := input.listRoute(PostMethod)
+	if errMapping != nil {
+		err = errMapping
 		return
 	}
 
+	report.add(checkRouteCoverage(routerSource, listRoute, listPostRoute, coverage)...)
 	return
 }
 
@@ -80,6 +83,8 @@ const (
 	Golang fileExtension = ".go"
 	Text   fileExtension = ".txt"
 	Json   fileExtension = ".json"
+	Yaml   fileExtension = ".yaml"
+	Yml    fileExtension = ".yml"
 )
 
 func (f fileExtension) String() string {
@@ -91,12 +96,43 @@ func listFileExtension() []string {
 		Golang.String(),
 		Text.String(),
 		Json.String(),
+		Yaml.String(),
+		Yml.String(),
 	}
 }
 
 func mappingRoute(methodCheck method, routerFileDir, routerPrefix string) (listRoute []MappingRoute, err error) {
+	// Router file is read from its directory, so other Go files of the package can be read
+	absPath, errAbs := filepath.Abs(routerFileDir)
+	if errAbs != nil {
+		err = fmt.Errorf("mappingRoute open router file: %s", errAbs.Error())
+		return
+	}
+
+	return mappingRouteFS(os.DirFS(filepath.Dir(absPath)), filepath.Base(absPath), methodCheck, routerPrefix)
+}
+
+// mappingRouteFS - routes of router file or Go router package inside fsys, name is a slash separated path of fsys
+func mappingRouteFS(fsys fs.FS, name string, methodCheck method, routerPrefix string) (listRoute []MappingRoute, err error) {
+	// Go router package is parsed by go/ast, directory is parsed as a package
+	if info, errStat := fs.Stat(fsys, name); errStat == nil && (info.IsDir() || path.Ext(name) == Golang.String()) {
+		listSource, errRead := readGoPackage(fsys, name)
+		if errRead != nil {
+			err = errRead
+			return
+		}
+
+		listRoute, err = mappingRouteGoSource(listSource, methodCheck)
+		if err != nil {
+			return
+		}
+
+		listRoute = appendRouterPrefix(listRoute, routerPrefix)
+		return
+	}
+
 	// Find path from router file
-	bte, errRead := os.ReadFile(routerFileDir)
+	bte, errRead := fs.ReadFile(fsys, name)
 	if errRead != nil {
 		err = fmt.Errorf("mappingRoute open router file: %s", errRead.Error())
 		return
@@ -104,15 +140,27 @@ func mappingRoute(methodCheck method, routerFileDir, routerPrefix string) (listR
 
 	// Switch extension file
 switchExt:
-	switch fileExt := filepath.Ext(routerFileDir); fileExt {
-	case Golang.String(), Text.String():
+	switch fileExt := path.Ext(name); fileExt {
+	case Text.String():
 		{
 			listRoute = mappingRouteRawCodeFile(bte, methodCheck)
 			break switchExt
 		}
 	case Json.String():
 		{
-			listRoute, err = mappingRouteJsonFile(bte, methodCheck)
+			if isOpenAPIDocument(bte) {
+				listRoute, err = mappingRouteOpenAPIFile(bte, methodCheck)
+			} else {
+				listRoute, err = mappingRouteJsonFile(bte, methodCheck)
+			}
+			if err != nil {
+				return
+			}
+			break switchExt
+		}
+	case Yaml.String(), Yml.String():
+		{
+			listRoute, err = mappingRouteOpenAPIFile(bte, methodCheck)
 			if err != nil {
 				return
 			}
@@ -123,23 +171,27 @@ switchExt:
 			strSupportedExt := strings.Join(listFileExtension(), " | ")
 			suffixMessage := fmt.Sprintf("Please use one of this extensions: %s", strSupportedExt)
 			if lib.IsEmptyStr(fileExt) {
-				err = fmt.Errorf("mappingRoute switch file extension: file extension %s of path %s is not supported. %s", fileExt, routerFileDir, suffixMessage)
+				err = fmt.Errorf("mappingRoute switch file extension: file extension %s of path %s is not supported. %s", fileExt, name, suffixMessage)
 			} else {
-				err = fmt.Errorf("mappingRoute switch file extension: please declare your file extension of path %s. %s", routerFileDir, suffixMessage)
+				err = fmt.Errorf("mappingRoute switch file extension: please declare your file extension of path %s. %s", name, suffixMessage)
 			}
 			break switchExt
 		}
 	}
 
-	// Append routerPrefix
+	listRoute = appendRouterPrefix(listRoute, routerPrefix)
+	return
+}
+
+// appendRouterPrefix - prepend routerPrefix to path of every route
+func appendRouterPrefix(listRoute []MappingRoute, routerPrefix string) []MappingRoute {
 	if !lib.IsEmptyStr(routerPrefix) {
 		for i := 0; i < len(listRoute); i++ {
 			existPath := listRoute[i].Path
 			listRoute[i].Path = routerPrefix + existPath
 		}
 	}
-
-	return
+	return listRoute
 }
 
 /*
@@ -222,8 +274,7 @@ func (m *mapValidateRoute) add(k, v string) {
 	}
 }
 
-func validateRoute(routerSource RouterSource, listRoute []MappingRoute) (err error) {
-	arrMessage := []string{}
+func validateRoute(routerSource RouterSource, listRoute []MappingRoute) (report ValidationReport) {
 	mapPatternMatchRoute := newMapValidateRoute()
 	mapRouteMatchPattern := newMapValidateRoute()
 
@@ -233,10 +284,27 @@ loopFirstRouterSource:
 		// Try compile pattern
 		pattern, errCompile := regexp.Compile(routePattern)
 		if errCompile != nil {
-			arrMessage = append(arrMessage, fmt.Sprintf("failed compile route pattern: %s, message: %s", routePattern, errCompile.Error()))
+			report.add(Issue{
+				Code:       IssuePatternInvalid,
+				Severity:   SeverityError,
+				Pattern:    routePattern,
+				Message:    fmt.Sprintf("failed compile route pattern: %s, message: %s", routePattern, errCompile.Error()),
+				Suggestion: "Fix the regular expression of the pattern",
+			})
 			continue loopFirstRouterSource
 		}
 
+		// Id of deleted data is captured by the first group, pattern without group never protects data
+		if pattern.NumSubexp() == 0 {
+			report.add(Issue{
+				Code:       IssuePatternNoIDGroup,
+				Severity:   SeverityError,
+				Pattern:    routePattern,
+				Message:    fmt.Sprintf("pattern %s has no group to capture the id", routePattern),
+				Suggestion: "Capture the id with a group, ex: UseMasterPattern(\"cities\")",
+			})
+		}
+
 	loopListRoute:
 		for _, route := range listRoute {
 			// Init
@@ -259,22 +327,32 @@ loopMapPatternMatchRoute:
 	for k, v := range *mapPatternMatchRoute {
 		// Indicate pattern not match any route
 		if len(v) == 0 {
-			arrMessage = append(arrMessage, fmt.Sprintf("pattern %s is not match any route", k))
+			report.add(Issue{
+				Code:       IssuePatternUnmatched,
+				Severity:   SeverityError,
+				Pattern:    k,
+				Message:    fmt.Sprintf("pattern %s is not match any route", k),
+				Suggestion: "Remove the pattern, or check the DELETE route and router prefix of the router file",
+			})
 			continue loopMapPatternMatchRoute
 		}
 
 		// Indicates the pattern match more than one route
 		if len(v) > 1 {
-			strRoutes := strings.Join(v, " ,\n ")
-			arrMessage = append(arrMessage, fmt.Sprintf("the pattern match more than one route [%s]: \n%s", k, strRoutes))
+			for _, route := range v {
+				report.add(Issue{
+					Code:       IssuePatternAmbiguous,
+					Severity:   SeverityError,
+					Pattern:    k,
+					Route:      route,
+					Message:    fmt.Sprintf("the pattern match more than one route [%s]: %s", k, route),
+					Suggestion: "Anchor the pattern, ex: end the pattern with $, so it only matches one route",
+				})
+			}
 			continue loopMapPatternMatchRoute
 		}
 	}
 
-	// Log result
-	bytePatternMatchRoute, _ := json.MarshalIndent(mapPatternMatchRoute, "", " ")
-	log.Println("validateRoute mapPatternMatchRoute: \n", string(bytePatternMatchRoute))
-
 loopMapRouteMatchPattern:
 	for k, v := range *mapRouteMatchPattern {
 		if v == nil || len(v) == 1 {
@@ -283,64 +361,48 @@ loopMapRouteMatchPattern:
 
 		// Indicates the route match more than one pattern
 		if len(v) > 1 {
-			strPatterns := strings.Join(v, " ,\n ")
-			arrMessage = append(arrMessage, fmt.Sprintf("the route match more than one pattern [%s]: \n%s", k, strPatterns))
+			for _, pattern := range v {
+				report.add(Issue{
+					Code:       IssueRouteAmbiguous,
+					Severity:   SeverityError,
+					Pattern:    pattern,
+					Route:      k,
+					Message:    fmt.Sprintf("the route match more than one pattern [%s]: %s", k, pattern),
+					Suggestion: "Remove the duplicated pattern, or make the patterns more specific",
+				})
+			}
 			continue loopMapRouteMatchPattern
 		}
 	}
 
-	// Log result
-	byteRouteMatchPattern, _ := json.MarshalIndent(mapRouteMatchPattern, "", " ")
-	log.Println("validateRoute mapRouteMatchPattern: \n", string(byteRouteMatchPattern))
-
-	// Return err
-	if len(arrMessage) > 0 {
-		message := strings.Join(arrMessage, ";\n")
-		err = fmt.Errorf("ERROR validateRoute: \n %s", message)
-		return
-	}
-
 	return
 }
 
-func checkValidTable(db *gorm.DB, routerSource RouterSource, modelMigrations []interface{}) (err error) {
-	arrMessage := []string{}
-
-	formatErr := func(section string, listMessage ...string) error {
-		message := strings.Join(listMessage, ";\n")
-		return fmt.Errorf("ERROR checkValidTable %s: \n %s", section, message)
-	}
-
+func checkValidTable(db *gorm.DB, routerSource RouterSource, modelMigrations []interface{}) (report ValidationReport) {
 	// Get list migration table from list migration model
-	listMigrationTable, arrMessage1 := getListMigrationTable(db, modelMigrations)
-	if len(arrMessage1) > 0 {
-		arrMessage = append(arrMessage, arrMessage1...)
-	}
-
-	// Log err
-	if len(arrMessage) > 0 {
-		err = formatErr("getListMigrationTable", arrMessage...)
+	listMigrationTable, issues := getListMigrationTable(db, modelMigrations)
+	if len(issues) > 0 {
+		report.add(issues...)
 		return
 	}
 
 	// Compare model migrations with router table
-	arrMessage2 := matchingModelMigrationsWithRouterSource(routerSource, listMigrationTable)
-	if len(arrMessage2) > 0 {
-		arrMessage = append(arrMessage, arrMessage2...)
-	}
-
-	// Log err
-	if len(arrMessage) > 0 {
-		err = formatErr("matchingModelMigrationsWithRouterSource", arrMessage...)
-		return
-	}
-
+	report.add(matchingModelMigrationsWithRouterSource(routerSource, listMigrationTable)...)
 	return
 }
 
-func getListMigrationTable(db *gorm.DB, modelMigrations []interface{}) (listMigrationTable, arrMessage []string) {
+func getListMigrationTable(db *gorm.DB, modelMigrations []interface{}) (listMigrationTable []string, issues []Issue) {
+	newIssue := func(message string) Issue {
+		return Issue{
+			Code:       IssueModelMigrationInvalid,
+			Severity:   SeverityError,
+			Message:    message,
+			Suggestion: "Declare model migrations with model pointers which are migrated, ex: []interface{}{&model.City{}}",
+		}
+	}
+
 	if len(modelMigrations) == 0 {
-		arrMessage = append(arrMessage, "error getListMigrationTable: model migrations is empty")
+		issues = append(issues, newIssue("error getListMigrationTable: model migrations is empty"))
 		return
 	}
 
@@ -349,13 +411,13 @@ loopModelMigrations:
 		assignModel := model
 		mod := db.Model(assignModel).Take(assignModel)
 		if mod.Error != nil && mod.Error != gorm.ErrRecordNotFound {
-			arrMessage = append(arrMessage, fmt.Sprintf("error getListMigrationTable when find table on index: %d, message: %s", idx, mod.Error.Error()))
+			issues = append(issues, newIssue(fmt.Sprintf("error getListMigrationTable when find table on index: %d, message: %s", idx, mod.Error.Error())))
 			continue loopModelMigrations
 		}
 
 		table := mod.Statement.Table
 		if lib.IsEmptyStr(table) {
-			arrMessage = append(arrMessage, fmt.Sprintf("error getListMigrationTable: table is empty on index: %d", idx))
+			issues = append(issues, newIssue(fmt.Sprintf("error getListMigrationTable: table is empty on index: %d", idx)))
 			continue loopModelMigrations
 		}
 
@@ -365,7 +427,7 @@ loopModelMigrations:
 	return
 }
 
-func matchingModelMigrationsWithRouterSource(routerSource RouterSource, listMigrationTable []string) (arrMessage []string) {
+func matchingModelMigrationsWithRouterSource(routerSource RouterSource, listMigrationTable []string) (issues []Issue) {
 	// Compare model migrations with router table
 loopRouterSource:
 	for pattern, source := range routerSource {
@@ -381,7 +443,13 @@ loopRouterSource:
 
 		// If source not match any model
 		if !isSourceMatch {
-			arrMessage = append(arrMessage, fmt.Sprintf("source %s on pattern %s not match any model. Make sure using model pointer on declare model migrations, ex: []interface{\n&model1{}, \n&model2{} \n}", source.Source, pattern))
+			issues = append(issues, Issue{
+				Code:       IssueSourceUnknown,
+				Severity:   SeverityError,
+				Pattern:    pattern,
+				Message:    fmt.Sprintf("source %s on pattern %s not match any model", source.Source, pattern),
+				Suggestion: fmt.Sprintf("Add model pointer of table %s to model migrations, ex: []interface{}{&model1{}, &model2{}}", source.Source),
+			})
 		}
 
 		// Validate required relation
@@ -400,11 +468,28 @@ loopRouterSource:
 
 				// If required relation not match any model
 				if !isRequiredMatch {
-					arrMessage = append(arrMessage, fmt.Sprintf("required relation %s index %d , on pattern %s not match any model", rTable, rIdx, pattern))
+					issues = append(issues, Issue{
+						Code:       IssueRequiredRelationUnknown,
+						Severity:   SeverityError,
+						Pattern:    pattern,
+						Message:    fmt.Sprintf("required relation %s index %d , on pattern %s not match any model", rTable, rIdx, pattern),
+						Suggestion: fmt.Sprintf("Add model pointer of table %s to model migrations, or remove it from RequiredRelation", rTable),
+					})
 					continue loopRequiredRelation
 				}
 			}
 
+			// Ignore relation is not used if required relation is declared
+			if len(source.IgnoreRelation) > 0 {
+				issues = append(issues, Issue{
+					Code:       IssueIgnoredRelationUnused,
+					Severity:   SeverityWarning,
+					Pattern:    pattern,
+					Message:    fmt.Sprintf("ignore relation on pattern %s is not used, because required relation is declared", pattern),
+					Suggestion: "Remove IgnoreRelation, only RequiredRelation is protected",
+				})
+			}
+
 			continue loopRouterSource
 		}
 
@@ -423,7 +508,13 @@ loopRouterSource:
 
 			// If ignore relation not match any model
 			if !isIgnoreMatch {
-				arrMessage = append(arrMessage, fmt.Sprintf("ignore relation %s index %d , on pattern %s not match any model", iTable, iIdx, pattern))
+				issues = append(issues, Issue{
+					Code:       IssueIgnoredRelationUnknown,
+					Severity:   SeverityError,
+					Pattern:    pattern,
+					Message:    fmt.Sprintf("ignore relation %s index %d , on pattern %s not match any model", iTable, iIdx, pattern),
+					Suggestion: fmt.Sprintf("Add model pointer of table %s to model migrations, or remove it from IgnoreRelation", iTable),
+				})
 				continue loopIgnoreRelation
 			}
 		}
@@ -145,7 +145,11 @@ func Test_checkValidRoute(t *testing.T) {
 				}
 			})
 
-			if err := checkValidRoute(tt.args.methodCheck, tt.args.routerSource, tt.args.routerFileDir, tt.args.routerPrefix); (err != nil) != tt.wantErr {
+			report, err := checkValidRoute(tt.args.methodCheck, tt.args.routerSource, RouteInputFromFile(tt.args.routerFileDir, tt.args.routerPrefix), CoverageConfig{})
+			if err == nil {
+				err = report.Err()
+			}
+			if (err != nil) != tt.wantErr {
 				t.Errorf("checkValidRoute() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -536,7 +540,7 @@ func Test_validateRoute(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := validateRoute(tt.args.routerSource, tt.args.listRoute)
+			err := validateRoute(tt.args.routerSource, tt.args.listRoute).Err()
 			if (err != nil) != tt.wantErr {
 				t.Errorf("validateRoute() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -629,7 +633,7 @@ func Test_checkValidTable(t *testing.T) {
 				sqlDB.Close()
 			})
 
-			if err := checkValidTable(tt.args.db, tt.args.routerSource, tt.args.modelMigrations); (err != nil) != tt.wantErr {
+			if err := checkValidTable(tt.args.db, tt.args.routerSource, tt.args.modelMigrations).Err(); (err != nil) != tt.wantErr {
 				t.Errorf("checkValidTable() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -706,11 +710,11 @@ func Test_getListMigrationTable(t *testing.T) {
 				sqlDB.Close()
 			})
 
-			gotListMigrationTable, gotArrMessage := getListMigrationTable(tt.args.db, tt.args.modelMigrations)
+			gotListMigrationTable, gotIssues := getListMigrationTable(tt.args.db, tt.args.modelMigrations)
 			if !reflect.DeepEqual(gotListMigrationTable, tt.wantListMigrationTable) {
 				t.Errorf("getListMigrationTable() gotListMigrationTable = %v, want %v", gotListMigrationTable, tt.wantListMigrationTable)
 			}
-			testSupport__validateArrMessage(t, gotArrMessage, tt.wantArrMessageContains)
+			testSupport__validateArrMessage(t, testSupport__issueMessages(gotIssues), tt.wantArrMessageContains)
 		})
 	}
 }
@@ -766,7 +770,7 @@ func Test_matchingModelMigrationsWithRouterSource(t *testing.T) {
 			wantArrMessageContains: []string{},
 		},
 		{
-			name: "some relation required and ignore, must only validate required relation, not error",
+			name: "some relation required and ignore, must only validate required relation, warning",
 			args: args{
 				routerSource: RouterSource{
 					setDummyPattern("cities"): SourceRelation{
@@ -784,7 +788,9 @@ func Test_matchingModelMigrationsWithRouterSource(t *testing.T) {
 					"country",
 				},
 			},
-			wantArrMessageContains: []string{},
+			wantArrMessageContains: []string{
+				"is not used",
+			},
 		},
 		{
 			name: "relation ignore, model not found, error",
@@ -847,8 +853,8 @@ func Test_matchingModelMigrationsWithRouterSource(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotArrMessage := matchingModelMigrationsWithRouterSource(tt.args.routerSource, tt.args.listMigrationTable)
-			testSupport__validateArrMessage(t, gotArrMessage, tt.wantArrMessageContains)
+			gotIssues := matchingModelMigrationsWithRouterSource(tt.args.routerSource, tt.args.listMigrationTable)
+			testSupport__validateArrMessage(t, testSupport__issueMessages(gotIssues), tt.wantArrMessageContains)
 		})
 	}
 }
@@ -880,3 +886,10 @@ func testSupport__validateArrMessage(t *testing.T, gotArrMessage, wantArrMessage
 		))
 	utils.AssertEqual(t, true, len(notFoundMessage) == 0, printMessage)
 }
+
+func testSupport__issueMessages(issues []Issue) (listMessage []string) {
+	for _, issue := range issues {
+		listMessage = append(listMessage, issue.Message)
+	}
+	return
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"sort"
+)
+
+// Severity - severity of an issue of route validation
+type Severity string
+
+const (
+	// SeverityError - router source cannot be used, MappingRoute is failed
+	SeverityError Severity = "error"
+	// SeverityWarning - router source can be used, but may not protect as expected
+	SeverityWarning Severity = "warning"
+)
+
+// Code of an issue of route validation
+const (
+	IssuePatternInvalid          = "pattern_invalid"
+	IssuePatternNoIDGroup        = "pattern_no_id_group"
+	IssuePatternUnmatched        = "pattern_unmatched"
+	IssuePatternAmbiguous        = "pattern_ambiguous"
+	IssuePatternIDGroupInvalid   = "pattern_id_group_invalid"
+	IssuePatternExtraGroup       = "pattern_extra_group"
+	IssuePatternUnanchored       = "pattern_unanchored"
+	IssuePatternOverlap          = "pattern_overlap"
+	IssueRouteAmbiguous          = "route_ambiguous"
+	IssueRouteUncovered          = "route_uncovered"
+	IssueModelMigrationInvalid   = "model_migration_invalid"
+	IssueSourceUnknown           = "source_unknown"
+	IssueRequiredRelationUnknown = "required_relation_unknown"
+	IssueIgnoredRelationUnknown  = "ignored_relation_unknown"
+	IssueIgnoredRelationUnused   = "ignored_relation_unused"
+)
+
+// Issue - a problem of router source found by route validation
+type Issue struct {
+	Code       string   `json:"code"`
+	Severity   Severity `json:"severity"`
+	Pattern    string   `json:"pattern,omitempty"`
+	Route      string   `json:"route,omitempty"`
+	Message    string   `json:"message"`
+	Suggestion string   `json:"suggestion,omitempty"`
+}
+
+// ValidationReport - every issue of router source found by MappingRoute.
+// Issues are sorted by severity, code, pattern and route, so the report can be compared between runs.
+type ValidationReport struct {
+	Issues []Issue `json:"issues"`
+}
+
+// HasErrors - report has at least one issue with SeverityError
+func (r ValidationReport) HasErrors() bool {
+	return len(r.Errors()) > 0
+}
+
+// Errors - issues with SeverityError
+func (r ValidationReport) Errors() []Issue {
+	return r.filter(SeverityError)
+}
+
+// Warnings - issues with SeverityWarning
+func (r ValidationReport) Warnings() []Issue {
+	return r.filter(SeverityWarning)
+}
+
+// Err - *RouteValidationError of issues with SeverityError, nil if there is no error
+func (r ValidationReport) Err() error {
+	issues := r.Errors()
+	if len(issues) == 0 {
+		return nil
+	}
+
+	return &RouteValidationError{Issues: issues}
+}
+
+func (r ValidationReport) filter(severity Severity) (issues []Issue) {
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			issues = append(issues, issue)
+		}
+	}
+	return
+}
+
+// add - add issues, then keep the issues sorted
+func (r *ValidationReport) add(issues ...Issue) {
+	r.Issues = append(r.Issues, issues...)
+
+	sort.SliceStable(r.Issues, func(i, j int) bool {
+		a, b := r.Issues[i], r.Issues[j]
+		switch {
+		case a.Severity != b.Severity:
+			return a.Severity == SeverityError
+		case a.Code != b.Code:
+			return a.Code < b.Code
+		case a.Pattern != b.Pattern:
+			return a.Pattern < b.Pattern
+		default:
+			return a.Route < b.Route
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+func TestValidationReport(t *testing.T) {
+	listRoute := []MappingRoute{
+		{Method: "DELETE", Path: "/api/v1/master/cities/:id"},
+		{Method: "DELETE", Path: "/api/v1/master/countries/:id"},
+	}
+
+	report := validateRoute(RouterSource{
+		UseMasterPattern("cities"):   SourceRelation{Source: "city"},
+		".*/api/v1/master/cities/.*": SourceRelation{Source: "city"},
+		UseMasterPattern("zones"):    SourceRelation{Source: "zone"},
+		".*/countries/:id$":          SourceRelation{Source: "country"},
+	}, listRoute)
+	report.add(matchingModelMigrationsWithRouterSource(RouterSource{
+		UseMasterPattern("cities"): SourceRelation{
+			Source:           "city",
+			RequiredRelation: []string{"airport"},
+			IgnoreRelation:   []string{"city_translation"},
+		},
+	}, []string{"city", "airport"})...)
+
+	// Errors are listed before warnings, then sorted by code, pattern and route
+	codes := []string{}
+	for _, issue := range report.Issues {
+		codes = append(codes, issue.Code)
+	}
+	utils.AssertEqual(t, []string{
+		IssuePatternNoIDGroup,
+		IssuePatternNoIDGroup,
+		IssuePatternUnmatched,
+		IssueRouteAmbiguous,
+		IssueRouteAmbiguous,
+		IssueIgnoredRelationUnused,
+	}, codes, "validate codes")
+
+	utils.AssertEqual(t, true, report.HasErrors(), "validate has errors")
+	utils.AssertEqual(t, 5, len(report.Errors()), "validate errors")
+	utils.AssertEqual(t, 1, len(report.Warnings()), "validate warnings")
+
+	// Pattern without group never protects data
+	noIDGroup := report.Issues[0]
+	utils.AssertEqual(t, SeverityError, noIDGroup.Severity, "validate severity")
+
+	unmatched := report.Issues[2]
+	utils.AssertEqual(t, SeverityError, unmatched.Severity, "validate severity")
+	utils.AssertEqual(t, UseMasterPattern("zones"), unmatched.Pattern, "validate pattern")
+	utils.AssertEqual(t, true, unmatched.Suggestion != "", "validate suggestion")
+
+	ambiguous := report.Issues[3]
+	utils.AssertEqual(t, "/api/v1/master/cities/:id", ambiguous.Route, "validate route")
+
+	// Warning only report is not an error
+	warningReport := ValidationReport{Issues: report.Warnings()}
+	utils.AssertEqual(t, false, warningReport.HasErrors(), "validate warning only")
+	utils.AssertEqual(t, nil, warningReport.Err(), "validate warning only err")
+
+	// Report is machine readable
+	bte, err := json.Marshal(report)
+	utils.AssertEqual(t, nil, err, "marshal report")
+	decoded := ValidationReport{}
+	err = json.Unmarshal(bte, &decoded)
+	utils.AssertEqual(t, nil, err, "unmarshal report")
+	utils.AssertEqual(t, report, decoded, "validate decoded report")
+}
@@ -0,0 +1,34 @@
+package migration
+
+import (
+	"fmt"
+
+	ir "github.com/terra-discover/bbcrs-route-protection-lib/migration/internal/relation"
+)
+
+// Errors of model migrations, usable by errors.Is
+var (
+	// ErrInvalidMigrationModel - db, model migrations or requirement of migration is not valid
+	ErrInvalidMigrationModel = ir.ErrInvalidMigrationModel
+	// ErrModelSchema - schema of a model cannot be found, ex: model is not migrated yet
+	ErrModelSchema = ir.ErrModelSchema
+	// ErrColumnTableNotFound - no foreign column is found on model migrations
+	ErrColumnTableNotFound = ir.ErrColumnTableNotFound
+	// ErrColumnConflict - foreign column is already mapped to another table
+	ErrColumnConflict = ir.ErrColumnConflict
+)
+
+// MigrationError - MigrateRelation is failed on a section, ex: genTableRelationSchema.
+// Err may join several errors, ex: every foreign column which is not found.
+type MigrationError struct {
+	Section string
+	Err     error
+}
+
+func (e *MigrationError) Error() string {
+	return fmt.Sprintf("ERROR migrateRelation %s: \n%s", e.Section, e.Err.Error())
+}
+
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
@@ -31,10 +31,11 @@ func (cm *columnModel) Add(column string, model interface{}) (err error) {
 
 	if existModel, ok := (*cm)[column]; ok {
 		if existModel != model {
-			err = fmt.Errorf("columnModel of column %s is exists with model: %+v, so we can't add new model: %+v",
+			err = fmt.Errorf("columnModel of column %s is exists with model: %+v, so we can't add new model: %+v: %w",
 				column,
 				existModel,
 				model,
+				ErrColumnConflict,
 			)
 			return
 		}
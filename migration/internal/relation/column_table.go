@@ -34,10 +34,11 @@ func (ct *ColumnTable) Add(column, table string) (err error) {
 
 	if existTable, ok := (*ct)[column]; ok {
 		if existTable != table {
-			err = fmt.Errorf("columnModel of column %s is exists with table: %+v, so we can't add new table: %+v",
+			err = fmt.Errorf("columnModel of column %s is exists with table: %+v, so we can't add new table: %+v: %w",
 				column,
 				existTable,
 				table,
+				ErrColumnConflict,
 			)
 			return
 		}
@@ -0,0 +1,14 @@
+package relation
+
+import "errors"
+
+var (
+	// ErrInvalidMigrationModel - db, model pointers or requirement of MigrationModel is not valid
+	ErrInvalidMigrationModel = errors.New("invalid migration model")
+	// ErrModelSchema - schema of a model cannot be found, ex: model is not migrated yet
+	ErrModelSchema = errors.New("model schema is not found")
+	// ErrColumnTableNotFound - no foreign column is found on model migrations
+	ErrColumnTableNotFound = errors.New("column table not found")
+	// ErrColumnConflict - column is already mapped to another table, model or column list
+	ErrColumnConflict = errors.New("column is already mapped")
+)
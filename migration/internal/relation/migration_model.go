@@ -3,7 +3,6 @@ package relation
 import (
 	"errors"
 	"fmt"
-	"strings"
 
 	"gorm.io/gorm"
 )
@@ -48,24 +47,24 @@ func (m *MigrationModel) setErr() {
 switchCond:
 	switch {
 	case m.db == nil:
-		m.err = errors.New("db is nil")
+		m.err = fmt.Errorf("%w: db is nil", ErrInvalidMigrationModel)
 		break switchCond
 	case len(m.listModelPointers) == 0:
-		m.err = errors.New("length listModelPointers = 0")
+		m.err = fmt.Errorf("%w: length listModelPointers = 0", ErrInvalidMigrationModel)
 		break switchCond
 	case len(m.listModelPointers) > 0:
 		{
 		loopModelPointers:
 			for _, modelPointer := range m.listModelPointers {
 				if modelPointer == nil {
-					m.err = errors.New("cannot address model of listModelPointers")
+					m.err = fmt.Errorf("%w: cannot address model of listModelPointers", ErrInvalidMigrationModel)
 					break loopModelPointers
 				}
 			}
 			break switchCond
 		}
 	case !m.requirement.isFulfilled():
-		m.err = errors.New("requirement is not fulfilled")
+		m.err = fmt.Errorf("%w: requirement is not fulfilled", ErrInvalidMigrationModel)
 		break switchCond
 	default:
 		m.err = nil
@@ -84,7 +83,7 @@ func (m MigrationModel) ToColumnTable() (columnSource ColumnTable, columnUsedBy
 	columnSource = ColumnTable{}
 	columnUsedBy = ColumnTables{}
 
-	arrMessage := []string{}
+	listErr := []error{}
 
 loopModelPointers:
 	for _, model := range m.listModelPointers {
@@ -92,7 +91,7 @@ loopModelPointers:
 		assignModel := model
 		schema, errSchema := getSchema(m.db, assignModel)
 		if errSchema != nil {
-			arrMessage = append(arrMessage, errSchema.Error())
+			listErr = append(listErr, errSchema)
 			continue loopModelPointers
 		}
 		fields := schema.Fields
@@ -121,12 +120,11 @@ loopModelPointers:
 		}
 	}
 
-	if len(arrMessage) > 0 {
-		message := strings.Join(arrMessage, "\n -")
-		err = fmt.Errorf("ERROR MigrationModel.ToColumnTable: \n %s", message)
+	if len(listErr) > 0 {
+		err = fmt.Errorf("ERROR MigrationModel.ToColumnTable: \n %w", errors.Join(listErr...))
 		return
 	} else if len(columnSource) == 0 {
-		err = errors.New("ERROR MigrationModel.ToColumnTable: column table not found, make sure model migrations is listed (beside of table relation and relation_schema)")
+		err = fmt.Errorf("ERROR MigrationModel.ToColumnTable: %w, make sure model migrations is listed (beside of table relation and relation_schema)", ErrColumnTableNotFound)
 		return
 	}
 
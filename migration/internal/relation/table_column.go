@@ -26,10 +26,11 @@ func (tc *tableColumn) Add(table string, column []string) (err error) {
 	if existColumn, ok := (*tc)[table]; ok {
 		sameLength, sameValues := lib.CompareSliceStr(existColumn, column)
 		if !sameValues || !sameLength {
-			err = fmt.Errorf("tableColumn of table %s is exists with column: %+v, so we can't add new column: %+v",
+			err = fmt.Errorf("tableColumn of table %s is exists with column: %+v, so we can't add new column: %+v: %w",
 				table,
 				existColumn,
 				column,
+				ErrColumnConflict,
 			)
 			return
 		}
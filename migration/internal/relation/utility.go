@@ -1,7 +1,6 @@
 package relation
 
 import (
-	"errors"
 	"fmt"
 
 	"gorm.io/gorm"
@@ -10,7 +9,7 @@ import (
 
 func getSchema(db *gorm.DB, model interface{}) (s *schema.Schema, err error) {
 	if model == nil {
-		err = errors.New("getSchema, model must not nil")
+		err = fmt.Errorf("getSchema, model must not nil: %w", ErrModelSchema)
 		return
 	}
 
@@ -23,14 +22,14 @@ func getSchema(db *gorm.DB, model interface{}) (s *schema.Schema, err error) {
 	makeStmt := tempDB.Model(model).Take(model)
 	if errStmt := makeStmt.Error; errStmt != nil && errStmt != gorm.ErrRecordNotFound {
 		if errStmt == gorm.ErrUnsupportedRelation {
-			err = fmt.Errorf("getSchema, error on finding model %+v, message: %s. %s", model, errStmt.Error(), "Please make sure you have migrate this model.")
+			err = fmt.Errorf("getSchema, error on finding model %+v, message: %w. Please make sure you have migrate this model: %w", model, errStmt, ErrModelSchema)
 			return
 		}
-		err = fmt.Errorf("getSchema, error on finding model %+v, message: %s", model, errStmt.Error())
+		err = fmt.Errorf("getSchema, error on finding model %+v, message: %w: %w", model, errStmt, ErrModelSchema)
 		return
 	}
 	if makeStmt.Statement == nil || makeStmt.Statement.Schema == nil {
-		err = fmt.Errorf("getSchema, statement or schema result of model %+v is nil", model)
+		err = fmt.Errorf("getSchema, statement or schema result of model %+v is nil: %w", model, ErrModelSchema)
 		return
 	}
 	s = makeStmt.Statement.Schema
@@ -1,7 +1,7 @@
 package migration
 
 import (
-	"fmt"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -19,8 +19,9 @@ type Migration struct {
 	IsMigrated  bool
 	Error       error
 
-	env Environment
-	db  *gorm.DB
+	env                 Environment
+	db                  *gorm.DB
+	afterMigrateHandler []func()
 }
 
 func NewMigration(env Environment, db *gorm.DB) (m *Migration) {
@@ -33,6 +34,7 @@ func NewMigration(env Environment, db *gorm.DB) (m *Migration) {
 
 type IMigration interface {
 	MigrateRelation(migrationsModel []interface{}, removeOldData bool) *Migration
+	AfterMigrateRelation(handler func()) *Migration
 
 	newSession()
 	isErrorEmpty() bool
@@ -49,6 +51,24 @@ func (m *Migration) MigrateRelation(migrationsModel []interface{}, removeOldData
 	err := migrateRelation(m.db, migrationsModel, removeOldData)
 	m.checkIsMigrated()
 	m.setError(err)
+
+	if err == nil {
+		for _, handler := range m.afterMigrateHandler {
+			handler()
+		}
+	}
+
+	return m
+}
+
+// AfterMigrateRelation - register handler to be called after MigrateRelation succeed,
+// ex: invalidate cached relation schema
+func (m *Migration) AfterMigrateRelation(handler func()) *Migration {
+	m.newSession()
+
+	if handler != nil {
+		m.afterMigrateHandler = append(m.afterMigrateHandler, handler)
+	}
 	return m
 }
 
@@ -79,7 +99,7 @@ func (m *Migration) setError(newError error) {
 	if m.isErrorEmpty() {
 		m.Error = newError
 	} else if newError != nil {
-		m.Error = fmt.Errorf("%v; %w", m.Error, newError)
+		m.Error = errors.Join(m.Error, newError)
 	}
 }
 
@@ -1,6 +1,7 @@
 package migration
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -186,8 +187,8 @@ var listCachingPrefixTable = []string{
 
 // migrateRelation - Must called after all tables has migrated
 func migrateRelation(db *gorm.DB, migrationsModel []interface{}, removeOldData bool) error {
-	formatErr := func(section, message string) error {
-		return fmt.Errorf("ERROR migrateRelation %s: \n%s", section, message)
+	formatErr := func(section string, err error) error {
+		return &MigrationError{Section: section, Err: err}
 	}
 
 	// map[foreign_key]table_name
@@ -200,7 +201,7 @@ func migrateRelation(db *gorm.DB, migrationsModel []interface{}, removeOldData b
 	requirement := ir.SetRequirement(mustFieldSuffix, avoidFields)
 	mapMigrationColumnSource, mapMigrationColumnUsedBy, err := ir.NewMigrationModel(db, migrationsModel, requirement).ToColumnTable()
 	if err != nil {
-		return err
+		return formatErr("ToColumnTable", err)
 	}
 
 	// Get list main table only
@@ -209,10 +210,10 @@ func migrateRelation(db *gorm.DB, migrationsModel []interface{}, removeOldData b
 	// Get special foreign column table
 	specialForeignColumnTable, err := mapSpecialForeignColumnName.ToColumnTable(db)
 	if err != nil {
-		return err
+		return formatErr("mapSpecialForeignColumnName", err)
 	}
 
-	arrMessage := []string{}
+	listErr := []error{}
 
 loopSchemaColumns:
 	for columnSource, usedByTables := range mapMigrationColumnUsedBy {
@@ -238,22 +239,20 @@ loopSchemaColumns:
 		}
 		isIncluded, err := includeColumnSection(reqInclude)
 		if err != nil {
-			arrMessage = append(arrMessage, err.Error())
+			listErr = append(listErr, err)
 			continue loopSchemaColumns
 		} else if isIncluded {
 			continue loopSchemaColumns
 		}
 
 		// 7. If not found, return err
-		arrMessage = append(arrMessage, fmt.Sprintf("schema column of column name: %s (used by table: %s), is not found", columnSource, usedByTables))
+		listErr = append(listErr, fmt.Errorf("schema column of column name: %s (used by table: %s), is not found", columnSource, usedByTables))
 		continue loopSchemaColumns
 	}
 
 	// Return err
-	if len(arrMessage) > 0 {
-		message := strings.Join(arrMessage, " \n -")
-		err = formatErr("mapMigrationColumnUsedBy", message)
-		return err
+	if len(listErr) > 0 {
+		return formatErr("mapMigrationColumnUsedBy", errors.Join(listErr...))
 	}
 
 	// Map source and usedby and append to relation schema
@@ -266,7 +265,7 @@ loopSchemaColumns:
 	err = genTableRelationSchema(tx)
 	if err != nil {
 		tx.Rollback()
-		return formatErr("genTableRelationSchema", err.Error())
+		return formatErr("genTableRelationSchema", err)
 	}
 
 	// Remove old relation schema
@@ -274,7 +273,7 @@ loopSchemaColumns:
 		err = mustDeleteOldData(tx)
 		if err != nil {
 			tx.Rollback()
-			return formatErr("mustDeleteOldData", err.Error())
+			return formatErr("mustDeleteOldData", err)
 		}
 	}
 
@@ -286,7 +285,7 @@ loopSchemaColumns:
 		DoNothing: true,
 	}).CreateInBatches(&listRelationSchema, 100).Error; err != nil {
 		tx.Rollback()
-		return formatErr("CreateInBatches relation schema", err.Error())
+		return formatErr("CreateInBatches relation schema", err)
 	}
 
 	// Commit tx
@@ -369,8 +368,8 @@ includeColumnSection --Included Column Section--
  6. If not found, Find foreign table name in list table name
 */
 func includeColumnSection(req includeColumnSectionRequest) (isIncluded bool, err error) {
-	formatErr := func(section, message string) error {
-		return fmt.Errorf("ERROR includeColumnSection %s: \n%s", section, message)
+	formatErr := func(section string, err error) error {
+		return fmt.Errorf("ERROR includeColumnSection %s: \n%w", section, err)
 	}
 
 	var (
@@ -387,7 +386,7 @@ loopSpecial:
 		if columnSource == specialColumn {
 			errAdd := mapSourceForeignColumn.Add(specialColumn, specialTable)
 			if errAdd != nil {
-				err = formatErr("loop specialForeignColumnTable", errAdd.Error())
+				err = formatErr("loop specialForeignColumnTable", errAdd)
 				break loopSpecial
 			}
 			isIncluded = true
@@ -408,7 +407,7 @@ loopMain:
 		if mainTable == foreignTable {
 			errAdd := mapSourceForeignColumn.Add(columnSource, foreignTable)
 			if errAdd != nil {
-				err = formatErr("loop listMainTable", errAdd.Error())
+				err = formatErr("loop listMainTable", errAdd)
 				break loopMain
 			}
 			isIncluded = true
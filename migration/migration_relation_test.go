@@ -1,6 +1,7 @@
 package migration
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
@@ -100,6 +101,28 @@ func Test__migrateRelation(t *testing.T) {
 	utils.AssertEqual(t, false, !lib.IsEmptyStr(showErr), "Err desc: \n"+showErr)
 }
 
+func Test__migrateRelationError(t *testing.T) {
+	db := testSupport__DBConnectAndSeedTest()
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	// Empty model migrations
+	err := migrateRelation(db, []interface{}{}, false)
+
+	var errMigration *MigrationError
+	utils.AssertEqual(t, true, errors.As(err, &errMigration), "validate MigrationError")
+	utils.AssertEqual(t, "ToColumnTable", errMigration.Section, "validate section")
+	utils.AssertEqual(t, true, errors.Is(err, ErrInvalidMigrationModel), "validate ErrInvalidMigrationModel")
+
+	// Error of migration is joined
+	m := NewMigration(Environment{}, db)
+	m.setError(errors.New("first"))
+	m.setError(err)
+	utils.AssertEqual(t, true, errors.Is(m.Error, ErrInvalidMigrationModel), "validate joined error")
+}
+
 func Test_mustDeleteOldData(t *testing.T) {
 	db := testSupport__DBConnectAndSeedTest()
 	t.Cleanup(func() {
@@ -1,11 +1,12 @@
 package routeprotection
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"log"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"github.com/terra-discover/bbcrs-helper-lib/pkg/lib"
 	"github.com/terra-discover/bbcrs-route-protection-lib/middleware"
@@ -20,6 +21,8 @@ type Environment struct {
 
 type RouteProtection struct {
 	Error error
+	// Report - issues of router source found by the last MappingRoute
+	Report middleware.ValidationReport
 
 	migration  *migration.Migration
 	middleware *middleware.Middleware
@@ -38,13 +41,37 @@ func NewRouteProtection(env Environment, db *gorm.DB) (rp *RouteProtection) {
 	md := middleware.NewMiddleware(middleware.Environment(env), db)
 	rp.setMiddleware(md)
 
+	// Cached route maps must follow the latest relation schema
+	mg.AfterMigrateRelation(func() {
+		md.Refresh()
+	})
+
 	return
 }
 
 type IRouteProtection interface {
 	MigrateRelation(migrationsModel []interface{}) *RouteProtection
 	MappingRoute(newRouterSource middleware.RouterSource) *RouteProtection
+	MappingRouteInput(newRouterSource middleware.RouterSource, modelMigrations []interface{}, input middleware.RouteInput) *RouteProtection
 	ProtectRoute(c *fiber.Ctx) *RouteProtection
+	Protect(c *fiber.Ctx) error
+	Check(c *fiber.Ctx) (decision middleware.Decision, err error)
+	Dependencies(c *fiber.Ctx) error
+	CanDelete(ctx context.Context, tableName string, ids []uuid.UUID) (decision middleware.Decision, err error)
+	Plugin() *middleware.Plugin
+	DeleteProtected(ctx context.Context, tx *gorm.DB, model interface{}, ids []uuid.UUID) (decision middleware.Decision, err error)
+	Handler(config ...Config) fiber.Handler
+	ReplaceRouterSource(newRouterSource middleware.RouterSource) *RouteProtection
+	RemoveRouterSource(patterns ...string) *RouteProtection
+	ResetRouterSource() *RouteProtection
+	Refresh() *RouteProtection
+	SetProbeConcurrency(concurrency int) *RouteProtection
+	SetModelMigrations(modelMigrations []interface{}) *RouteProtection
+	SetFailurePolicy(policy middleware.FailurePolicy, callback ...middleware.FailureCallback) *RouteProtection
+	SetViolationSampleSize(size int) *RouteProtection
+	SetTenancy(tenantColumn string, resolver ...middleware.TenantResolver) *RouteProtection
+	SetTransitive(isTransitive bool) *RouteProtection
+	SetCoverage(coverage middleware.CoverageConfig) *RouteProtection
 
 	newSession()
 	isErrorEmpty() bool
@@ -67,23 +94,31 @@ func (rp *RouteProtection) MigrateRelation(removeOldData bool, modelMigrations [
 
 // MappingRoute - will validate and compare all model migrations with listed router
 //
-// @Params routerFileDir, use to validate listed endpoint by Regex.
+// @Params routerFileDir, use to validate listed endpoint.
+// Go file or directory of Go router package is parsed by go/ast, Group and Route prefixes are resolved automatically.
+// Other Go files in the same directory are used to resolve constants and functions receiving a router.
+// Example:
+//
+//	api := app.Group("/api/v1/my-prefix")
+//	api.Delete(myPath+"/:id", myController)
+//
+// Text file is parsed by Regex.
 // Example:
 //
 //	app.Get(`/my-endpoint/:id`, myController)
 //
+// OpenAPI 3 document (.yaml, .yml, or .json object) is parsed from paths, prefixed by path of servers URL.
+// Path template is normalized, ex: /my-endpoint/{id} to /my-endpoint/:id
+//
 // @Params routerPrefix. Can be empty string if router file already describe prefix in every endpoints
 // Example:
 //
 //	"/api/v1/master"
 //
-// If your router file not describe prefix *statically*, you must fill routerPrefix.
-// Example:
-//
-//	api := app.Group("/api/v1/my-prefix")
-//	app.Get(`/my-endpoint/:id`, myController)
+// If your router file not describe prefix *statically*, ex: the group is created outside of the router package,
+// you must fill routerPrefix.
 //
-// In this case, you must fill @Params routerPrefix = "/api/v1/my-prefix"
+// Every issue is listed on Report, including warnings which do not set Error.
 func (rp *RouteProtection) MappingRoute(newRouterSource middleware.RouterSource, modelMigrations []interface{}, routerFileDir, routerPrefix string) *RouteProtection {
 	rp.newSession()
 
@@ -92,12 +127,20 @@ func (rp *RouteProtection) MappingRoute(newRouterSource middleware.RouterSource,
 		return rp
 	}
 
-	err := rp.middleware.MappingRoute(newRouterSource, modelMigrations, routerFileDir, routerPrefix).Error
-	rp.setError(err)
+	md := rp.middleware.MappingRoute(newRouterSource, modelMigrations, routerFileDir, routerPrefix)
+	rp.Report = md.Report
+	rp.setError(md.Error)
 	return rp
 }
 
-func (rp *RouteProtection) ProtectRoute(c *fiber.Ctx) *RouteProtection {
+// MappingRouteInput - will validate and compare all model migrations with routes of input.
+// Use middleware.RouteInputFromApp to validate routes registered on fiber app, when router file is not shipped,
+// or middleware.RouteInputFromFS to validate router files of embed.FS, each glob pattern with its own prefix.
+// Fiber app with mounted sub app must be started first, ex: app.Handler().
+// Example:
+//
+//	rp.MappingRouteInput(routerSource, modelMigrations, middleware.RouteInputFromApp(app))
+func (rp *RouteProtection) MappingRouteInput(newRouterSource middleware.RouterSource, modelMigrations []interface{}, input middleware.RouteInput) *RouteProtection {
 	rp.newSession()
 
 	if err := rp.isMigrated(); err != nil {
@@ -105,7 +148,185 @@ func (rp *RouteProtection) ProtectRoute(c *fiber.Ctx) *RouteProtection {
 		return rp
 	}
 
-	err := rp.middleware.ProtectRoute(c).Error
+	md := rp.middleware.MappingRouteInput(newRouterSource, modelMigrations, input)
+	rp.Report = md.Report
+	rp.setError(md.Error)
+	return rp
+}
+
+// ProtectRoute - run data protection and save the result into Error.
+//
+// Deprecated: Error is shared by all requests, so the result can be overwritten by another request.
+// Use Protect or Handler instead.
+func (rp *RouteProtection) ProtectRoute(c *fiber.Ctx) *RouteProtection {
+	rp.newSession()
+
+	err := rp.Protect(c)
+	rp.setError(err)
+	return rp
+}
+
+// Protect - request scoped data protection, safe to be shared by all handlers.
+// Not allowed request will be responded with 405, otherwise continue to the next handler.
+func (rp *RouteProtection) Protect(c *fiber.Ctx) error {
+	if err := rp.isMigrated(); err != nil {
+		return err
+	}
+
+	return rp.middleware.Protect(c)
+}
+
+// CanDelete - decide whether ids of source table are allowed to be deleted, usable outside HTTP request.
+// Not allowed decision is returned with *ProtectionViolationError.
+func (rp *RouteProtection) CanDelete(ctx context.Context, tableName string, ids []uuid.UUID) (decision middleware.Decision, err error) {
+	if err = rp.isMigrated(); err != nil {
+		return
+	}
+
+	return rp.middleware.CanDelete(ctx, tableName, ids)
+}
+
+// DeleteProtected - lock source rows and referencing tables, probe dependent tables, then delete in the same transaction.
+// Only postgres and sqlite are supported, other databases return ErrDeleteLockUnsupported.
+// Not allowed decision is returned with *ProtectionViolationError, nothing is deleted.
+func (rp *RouteProtection) DeleteProtected(ctx context.Context, tx *gorm.DB, model interface{}, ids []uuid.UUID) (decision middleware.Decision, err error) {
+	if err = rp.isMigrated(); err != nil {
+		return
+	}
+
+	return rp.middleware.DeleteProtected(ctx, tx, model, ids)
+}
+
+// Plugin - make gorm plugin which protects every delete of source table, ex: db.Use(rp.Plugin())
+func (rp *RouteProtection) Plugin() *middleware.Plugin {
+	return rp.middleware.Plugin()
+}
+
+// Dependencies - preview dependent tables of a record before deletion.
+// Mount it on GET <resource>/:id/dependencies of a protected delete route.
+func (rp *RouteProtection) Dependencies(c *fiber.Ctx) error {
+	if err := rp.isMigrated(); err != nil {
+		return err
+	}
+
+	return rp.middleware.Dependencies(c)
+}
+
+// Check - request scoped data protection without responding and without calling the next handler.
+// Not allowed decision is returned with *ProtectionViolationError.
+func (rp *RouteProtection) Check(c *fiber.Ctx) (decision middleware.Decision, err error) {
+	if err = rp.isMigrated(); err != nil {
+		return
+	}
+
+	return rp.middleware.Check(c)
+}
+
+// ReplaceRouterSource - add router source of this instance, existing pattern will be replaced
+func (rp *RouteProtection) ReplaceRouterSource(newRouterSource middleware.RouterSource) *RouteProtection {
+	rp.newSession()
+
+	err := rp.middleware.ReplaceRouterSource(newRouterSource).Error
+	rp.setError(err)
+	return rp
+}
+
+// RemoveRouterSource - remove router source of this instance by patterns
+func (rp *RouteProtection) RemoveRouterSource(patterns ...string) *RouteProtection {
+	rp.newSession()
+
+	err := rp.middleware.RemoveRouterSource(patterns...).Error
+	rp.setError(err)
+	return rp
+}
+
+// ResetRouterSource - remove all router source of this instance
+func (rp *RouteProtection) ResetRouterSource() *RouteProtection {
+	rp.newSession()
+
+	err := rp.middleware.ResetRouterSource().Error
+	rp.setError(err)
+	return rp
+}
+
+// Refresh - reload relation schema on the next protected request.
+// Called automatically after MigrateRelation succeed.
+func (rp *RouteProtection) Refresh() *RouteProtection {
+	rp.newSession()
+
+	err := rp.middleware.Refresh().Error
+	rp.setError(err)
+	return rp
+}
+
+// SetProbeConcurrency - maximum dependent tables probed in parallel for one delete request.
+// Tables are always probed one by one inside a transaction, ex: DeleteProtected and Plugin.
+// Must be called before serving requests.
+func (rp *RouteProtection) SetProbeConcurrency(concurrency int) *RouteProtection {
+	rp.newSession()
+
+	err := rp.middleware.SetProbeConcurrency(concurrency).Error
+	rp.setError(err)
+	return rp
+}
+
+// SetFailurePolicy - decide what to do with the delete request when protection query is failed.
+// Handler replaces it if Config.Policy is set.
+// Default middleware.FailClosed. Must be called before serving requests.
+func (rp *RouteProtection) SetFailurePolicy(policy middleware.FailurePolicy, callback ...middleware.FailureCallback) *RouteProtection {
+	rp.newSession()
+
+	err := rp.middleware.SetFailurePolicy(policy, callback...).Error
+	rp.setError(err)
+	return rp
+}
+
+// SetViolationSampleSize - maximum referencing ids of each dependent table in 405 response.
+// Default 0, only total references are responded. Must be called before serving requests.
+func (rp *RouteProtection) SetViolationSampleSize(size int) *RouteProtection {
+	rp.newSession()
+
+	err := rp.middleware.SetViolationSampleSize(size).Error
+	rp.setError(err)
+	return rp
+}
+
+// SetTenancy - enable tenancy mode, only references of the requesting tenant block deletion, ex: agent_id.
+// Empty tenantColumn disables tenancy mode. Must be called before serving requests.
+func (rp *RouteProtection) SetTenancy(tenantColumn string, resolver ...middleware.TenantResolver) *RouteProtection {
+	rp.newSession()
+
+	err := rp.middleware.SetTenancy(tenantColumn, resolver...).Error
+	rp.setError(err)
+	return rp
+}
+
+// SetTransitive - check delete through ignored and cascaded relations, with cycle detection.
+// Default false. Must be called before serving requests.
+func (rp *RouteProtection) SetTransitive(isTransitive bool) *RouteProtection {
+	rp.newSession()
+
+	err := rp.middleware.SetTransitive(isTransitive).Error
+	rp.setError(err)
+	return rp
+}
+
+// SetCoverage - set allowlist and severity of DELETE routes which are not covered by router source.
+// Must be called before MappingRoute.
+func (rp *RouteProtection) SetCoverage(coverage middleware.CoverageConfig) *RouteProtection {
+	rp.newSession()
+
+	err := rp.middleware.SetCoverage(coverage).Error
+	rp.setError(err)
+	return rp
+}
+
+// SetModelMigrations - set model migrations to validate table and column of relation schema.
+// Already called by MappingRoute, only needed if router source is set by ReplaceRouterSource.
+func (rp *RouteProtection) SetModelMigrations(modelMigrations []interface{}) *RouteProtection {
+	rp.newSession()
+
+	err := rp.middleware.SetModelMigrations(modelMigrations).Error
 	rp.setError(err)
 	return rp
 }
@@ -128,7 +349,7 @@ func (rp *RouteProtection) isMigrated() (err error) {
 
 	// Set result
 	if !isMigrated {
-		err = errors.New("WARNING RouteProtection: No relation schema found, please MigrateRelation first")
+		err = ErrNotMigrated
 	}
 
 	return
@@ -154,7 +375,7 @@ func (rp *RouteProtection) setError(newError error) {
 	if rp.isErrorEmpty() {
 		rp.Error = newError
 	} else if newError != nil {
-		rp.Error = fmt.Errorf("%v; %w", rp.Error, newError)
+		rp.Error = errors.Join(rp.Error, newError)
 	}
 }
 